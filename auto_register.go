@@ -0,0 +1,127 @@
+package poly
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrDiscriminatorCollision is returned by AutoRegister (and is what
+// MustRegisterAll panics with) when two distinct Declared types resolve to
+// the same discriminator for P, or when one would clash with a general
+// registration already on the Registry.
+var ErrDiscriminatorCollision = errors.New("discriminator collision")
+
+var (
+	declaredMu    sync.Mutex
+	declaredTypes []reflect.Type
+)
+
+// Declare marks S as a candidate implementation for a later AutoRegister or
+// MustRegisterAll call. Call it from an init() in the package that defines
+// S, e.g.:
+//
+//	func init() { poly.Declare[EmailJob]() }
+//
+// Declare doesn't know which interface(s) S implements; AutoRegister/
+// MustRegisterAll filter every Declared type down to the ones implementing
+// the interface they're called for when they run. cmd/polyscan is a
+// build-time alternative that doesn't require this runtime call.
+func Declare[S any]() {
+	typ := reflect.TypeFor[S]()
+
+	declaredMu.Lock()
+	defer declaredMu.Unlock()
+	declaredTypes = append(declaredTypes, typ)
+}
+
+// A Namer derives the discriminator AutoRegister/MustRegisterAll assigns to
+// typ. The default, DefaultNamer, is typ's unqualified type name.
+type Namer func(typ reflect.Type) string
+
+// DefaultNamer returns typ's unqualified type name (e.g. "EmailJob"), with
+// any pointer indirection stripped first.
+func DefaultNamer(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// AutoRegisterOptions configures AutoRegister and MustRegisterAll.
+type AutoRegisterOptions struct {
+	// Registry to register discovered implementations on. Defaults to
+	// DefaultRegistry.
+	Registry *Registry
+	// Namer derives each implementation's discriminator. Defaults to
+	// DefaultNamer.
+	Namer Namer
+}
+
+func (opts AutoRegisterOptions) registry() *Registry {
+	if opts.Registry != nil {
+		return opts.Registry
+	}
+	return DefaultRegistry
+}
+
+func (opts AutoRegisterOptions) namer() Namer {
+	if opts.Namer != nil {
+		return opts.Namer
+	}
+	return DefaultNamer
+}
+
+// AutoRegister registers every type Declared so far that implements P (value
+// or pointer form, whichever satisfies it), using opts.Namer to assign each
+// a discriminator. It returns ErrDiscriminatorCollision, without registering
+// anything, if two Declared types implementing P would collide on the same
+// discriminator, or if a discriminator or type it would register already
+// has a different general registration on opts.Registry (e.g. from a prior
+// AutoRegister or manual RegisterIn call).
+func AutoRegister[P any](opts AutoRegisterOptions) error {
+	registry := opts.registry()
+	namer := opts.namer()
+	p := reflect.TypeFor[P]()
+
+	declaredMu.Lock()
+	declared := append([]reflect.Type(nil), declaredTypes...)
+	declaredMu.Unlock()
+
+	discriminators := make(map[string]reflect.Type)
+	for _, typ := range declared {
+		if !implementsInterface(typ, p) {
+			continue
+		}
+
+		discriminator := namer(typ)
+		if existing, ok := discriminators[discriminator]; ok && existing != typ {
+			return fmt.Errorf("%w: %q for both %v and %v", ErrDiscriminatorCollision, discriminator, existing, typ)
+		}
+		discriminators[discriminator] = typ
+	}
+
+	for discriminator, typ := range discriminators {
+		if existing, ok := registry.existingType(discriminator); ok && existing != typ {
+			return fmt.Errorf("%w: %q is already registered for %v, can't also use it for %v", ErrDiscriminatorCollision, discriminator, existing, typ)
+		}
+		if existing, ok := registry.existingDiscriminator(typ); ok && existing != discriminator {
+			return fmt.Errorf("%w: %v is already registered as %q, can't also register it as %q", ErrDiscriminatorCollision, typ, existing, discriminator)
+		}
+	}
+
+	for discriminator, typ := range discriminators {
+		registry.registerType(typ, discriminator)
+	}
+
+	return nil
+}
+
+// MustRegisterAll is AutoRegister, panicking instead of returning an error
+// (e.g. on a discriminator collision).
+func MustRegisterAll[P any](opts AutoRegisterOptions) {
+	if err := AutoRegister[P](opts); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,113 @@
+package poly_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/clickermonkey/poly"
+	"github.com/clickermonkey/poly/internal/autoregister/email"
+	"github.com/clickermonkey/poly/internal/autoregister/job"
+	"github.com/clickermonkey/poly/internal/autoregister/save"
+	"github.com/clickermonkey/poly/internal/autoregister/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoRegister(t *testing.T) {
+	r := poly.NewRegistry()
+	assert.NoError(t, poly.AutoRegister[job.Job](poly.AutoRegisterOptions{Registry: r}))
+
+	encoded, err := json.Marshal(poly.CIn[job.Job](r, email.EmailJob{Message: "hi"}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["EmailJob",{"message":"hi"}]`, string(encoded))
+
+	decoded := poly.T[job.Job]{Registry: r}
+	assert.NoError(t, json.Unmarshal([]byte(`["SaveJob",{"path":"/tmp/a"}]`), &decoded))
+	assert.Equal(t, "saving /tmp/a", decoded.Value.Do())
+
+	assert.NoError(t, json.Unmarshal([]byte(`["StateJob",{"done":2}]`), &decoded))
+	assert.Equal(t, "Do() #2", decoded.Value.Do())
+
+	encodedState, err := json.Marshal(poly.CIn[job.Job](r, &state.StateJob{Done: 3}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["StateJob",{"done":3}]`, string(encodedState))
+}
+
+func TestAutoRegister_CustomNamer(t *testing.T) {
+	r := poly.NewRegistry()
+	assert.NoError(t, poly.AutoRegister[job.Job](poly.AutoRegisterOptions{
+		Registry: r,
+		Namer: func(typ reflect.Type) string {
+			return "job:" + poly.DefaultNamer(typ)
+		},
+	}))
+
+	encoded, err := json.Marshal(poly.CIn[job.Job](r, save.SaveJob{Path: "/tmp/b"}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["job:SaveJob",{"path":"/tmp/b"}]`, string(encoded))
+}
+
+func TestAutoRegister_Collision(t *testing.T) {
+	r := poly.NewRegistry()
+	err := poly.AutoRegister[job.Job](poly.AutoRegisterOptions{
+		Registry: r,
+		Namer: func(typ reflect.Type) string {
+			return "job"
+		},
+	})
+	assert.True(t, errors.Is(err, poly.ErrDiscriminatorCollision))
+
+	// A failed AutoRegister registers nothing.
+	assert.Error(t, json.Unmarshal([]byte(`["job",{}]`), &poly.T[job.Job]{Registry: r}))
+}
+
+// fakeEmailJob exists only to occupy the "EmailJob" discriminator manually,
+// distinct from email.EmailJob, for TestAutoRegister_CollidesWithExisting.
+type fakeEmailJob struct{}
+
+func (fakeEmailJob) Do() string { return "fake" }
+
+func TestAutoRegister_CollidesWithExisting(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[fakeEmailJob](r, "EmailJob")
+
+	err := poly.AutoRegister[job.Job](poly.AutoRegisterOptions{Registry: r})
+	assert.True(t, errors.Is(err, poly.ErrDiscriminatorCollision))
+
+	// A failed AutoRegister doesn't clobber the registration already on r.
+	decoded := poly.T[job.Job]{Registry: r}
+	assert.NoError(t, json.Unmarshal([]byte(`["EmailJob",{}]`), &decoded))
+	assert.Equal(t, "fake", decoded.Value.Do())
+}
+
+func TestAutoRegister_CalledTwiceIsIdempotent(t *testing.T) {
+	r := poly.NewRegistry()
+	assert.NoError(t, poly.AutoRegister[job.Job](poly.AutoRegisterOptions{Registry: r}))
+	assert.NoError(t, poly.AutoRegister[job.Job](poly.AutoRegisterOptions{Registry: r}))
+}
+
+func TestAutoRegister_SecondCallWithDifferentNamerCollides(t *testing.T) {
+	r := poly.NewRegistry()
+	assert.NoError(t, poly.AutoRegister[job.Job](poly.AutoRegisterOptions{Registry: r}))
+
+	err := poly.AutoRegister[job.Job](poly.AutoRegisterOptions{
+		Registry: r,
+		Namer: func(typ reflect.Type) string {
+			return "other:" + poly.DefaultNamer(typ)
+		},
+	})
+	assert.True(t, errors.Is(err, poly.ErrDiscriminatorCollision))
+}
+
+func TestMustRegisterAll_PanicsOnCollision(t *testing.T) {
+	r := poly.NewRegistry()
+	assert.Panics(t, func() {
+		poly.MustRegisterAll[job.Job](poly.AutoRegisterOptions{
+			Registry: r,
+			Namer: func(typ reflect.Type) string {
+				return "job"
+			},
+		})
+	})
+}
@@ -0,0 +1,134 @@
+package poly_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/clickermonkey/poly"
+)
+
+// BenchmarkDiscriminator_Reflective and BenchmarkDiscriminator_Generated
+// compare the reflect-based Register/RegisterSpecialized path against the
+// reflection-free fast path RegisterGeneratedIn wires in, as emitted by
+// cmd/polygen. Both benchmark only discriminator resolution: the generated
+// path doesn't change how the boxed value itself is marshaled/unmarshaled,
+// which still goes through encoding/json reflection either way (see
+// BenchmarkUnmarshalJSON_Reflective/_Generated below for the end-to-end
+// cost, where that shared reflection work dominates).
+func BenchmarkDiscriminator_Reflective(b *testing.B) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	value := poly.CIn[Job](r, EmailJob{Message: "hi"})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = value.Discriminator()
+	}
+}
+
+func BenchmarkDiscriminator_Generated(b *testing.B) {
+	r := poly.NewRegistry()
+	poly.RegisterGeneratedIn[Job](
+		r,
+		func(v Job) (string, bool) {
+			switch v.(type) {
+			case EmailJob:
+				return "email", true
+			}
+			return "", false
+		},
+		func(discriminator string) (Job, bool) {
+			switch discriminator {
+			case "email":
+				return &EmailJob{}, true
+			}
+			return nil, false
+		},
+	)
+	value := poly.CIn[Job](r, EmailJob{Message: "hi"})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = value.Discriminator()
+	}
+}
+
+func BenchmarkUnmarshalJSON_Reflective(b *testing.B) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	encoded := []byte(`["email",{"message":"hi"}]`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decoded := poly.T[Job]{Registry: r}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshalJSON_DefaultRegistry and BenchmarkUnmarshalJSON_DefaultRegistry
+// exercise the plain poly.C/poly.Register path with no explicit T[P].Registry
+// set, the pattern every pre-scoped-registry caller used and still the
+// common case. T[P].registry() falls through to contextRegistry() on that
+// path, which must stay a single activeDecodeScopes atomic load when no
+// Registry.NewJSONDecoder/NewYAMLDecoder call is in flight anywhere, not a
+// goroutineID() stack walk on every Marshal/Unmarshal.
+func BenchmarkMarshalJSON_DefaultRegistry(b *testing.B) {
+	poly.Reset()
+	defer poly.Reset()
+	poly.Register[EmailJob]("email")
+	value := poly.C[Job](EmailJob{Message: "hi"})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalJSON_DefaultRegistry(b *testing.B) {
+	poly.Reset()
+	defer poly.Reset()
+	poly.Register[EmailJob]("email")
+	encoded := []byte(`["email",{"message":"hi"}]`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decoded := poly.T[Job]{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalJSON_Generated(b *testing.B) {
+	r := poly.NewRegistry()
+	poly.RegisterGeneratedIn[Job](
+		r,
+		func(v Job) (string, bool) {
+			switch v.(type) {
+			case EmailJob:
+				return "email", true
+			}
+			return "", false
+		},
+		func(discriminator string) (Job, bool) {
+			switch discriminator {
+			case "email":
+				return &EmailJob{}, true
+			}
+			return nil, false
+		},
+	)
+	encoded := []byte(`["email",{"message":"hi"}]`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decoded := poly.T[Job]{Registry: r}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
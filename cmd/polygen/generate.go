@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// The comment directive that marks a type for registration, e.g.:
+//
+//	//poly:register "email"
+//	type EmailJob struct { ... }
+const registerDirectivePrefix = "//poly:register "
+
+const polyImportPath = "github.com/clickermonkey/poly"
+
+// A //poly:register-annotated type. typeExpr is the form it's actually held
+// as when boxed in a poly.T[P] (value or pointer, whichever implements),
+// used in type switches. newExpr always constructs a pointer, matching
+// reflect.New in the reflect-based path, so a decoded value is addressable
+// regardless of which form implements the interface.
+type registration struct {
+	typeExpr      string // e.g. "EmailJob" or "*StateJob"
+	newExpr       string // e.g. "&EmailJob{}" or "&StateJob{}"
+	discriminator string
+	typ           types.Type // the declared (non-pointer) type, for Implements checks
+}
+
+// An interface P that at least one poly.T[P] instantiation was found for,
+// along with every registration that implements it.
+type interfaceDispatch struct {
+	name          string
+	registrations []registration
+}
+
+// Generate scans the Go package at dir and returns the source of a
+// "DO NOT EDIT" file that registers every //poly:register-annotated type it
+// finds with poly.Register, and wires a reflection-free fast path via
+// poly.RegisterGenerated for every poly.T[P] instantiation it can resolve
+// implementations for.
+//
+// This only replaces the reflect-based discriminator<->type lookup
+// (Registry.byType/byDiscriminator) with a generated type/string switch; it
+// does not generate per-field MarshalJSON/UnmarshalJSON/MarshalYAML/
+// UnmarshalYAML methods for T[P] itself. poly.go's tuple form no longer
+// walks json.Decoder's Token/re-slice dance to find the value's raw bytes
+// (see tokenize.go's scanJSONValue), but the value's own fields still go
+// through encoding/json's and yaml.v3's normal reflection once those bytes
+// are found (see T[P].MarshalJSON and unmarshalTupleJSON in poly.go), so
+// RegisterGenerated only pays for itself on the discriminator resolution
+// step and the tuple-framing scan, not the field marshaling underneath it.
+// A true zero-reflection codec would need generated Marshal/Unmarshal
+// methods per registered type, encoding each field directly instead of
+// through encoding/json/yaml.v3 reflection; that's a substantially larger
+// generator than this one and isn't implemented here.
+//
+// Only types and interfaces declared in the scanned package itself are
+// considered; if a registered type implements a found interface through a
+// single receiver style throughout (as is the norm for this pattern), the
+// generated dispatch is exact. Mixing a value receiver for one interface and
+// a pointer receiver for another on the same type is not supported.
+func Generate(dir string) ([]byte, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax | packages.NeedImports,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, fmt.Errorf("loading package at %s: %w", dir, err)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package at %s, found %d", dir, len(pkgs))
+	}
+	pkg := pkgs[0]
+	if len(pkg.Errors) > 0 {
+		return nil, fmt.Errorf("package at %s has errors: %v", dir, pkg.Errors)
+	}
+
+	ifaces := findPolyInterfaces(pkg)
+	registrations := findRegistrations(pkg, ifaces)
+
+	dispatches := make([]interfaceDispatch, 0, len(ifaces))
+	for name, iface := range ifaces {
+		dispatch := interfaceDispatch{name: name}
+		for _, reg := range registrations {
+			if types.Implements(reg.typ, iface) || types.Implements(types.NewPointer(reg.typ), iface) {
+				dispatch.registrations = append(dispatch.registrations, reg)
+			}
+		}
+		if len(dispatch.registrations) == 0 {
+			continue
+		}
+		sort.Slice(dispatch.registrations, func(i, j int) bool {
+			return dispatch.registrations[i].discriminator < dispatch.registrations[j].discriminator
+		})
+		dispatches = append(dispatches, dispatch)
+	}
+	sort.Slice(dispatches, func(i, j int) bool { return dispatches[i].name < dispatches[j].name })
+
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].discriminator < registrations[j].discriminator
+	})
+
+	return render(pkg.Name, registrations, dispatches)
+}
+
+// findPolyInterfaces walks pkg's syntax trees for poly.T[P] instantiations
+// and returns the distinct interfaces P found, keyed by their generated-code
+// name.
+func findPolyInterfaces(pkg *packages.Package) map[string]*types.Interface {
+	ifaces := map[string]*types.Interface{}
+
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var expr ast.Expr
+			var typeArgs []ast.Expr
+			switch e := n.(type) {
+			case *ast.IndexExpr:
+				expr, typeArgs = e, []ast.Expr{e.Index}
+			case *ast.IndexListExpr:
+				expr, typeArgs = e, e.Indices
+			default:
+				return true
+			}
+			if len(typeArgs) != 1 {
+				return true
+			}
+
+			named, ok := pkg.TypesInfo.TypeOf(expr).(*types.Named)
+			if !ok || named.Obj() == nil || named.Obj().Pkg() == nil {
+				return true
+			}
+			if named.Obj().Pkg().Path() != polyImportPath || named.Obj().Name() != "T" {
+				return true
+			}
+
+			argType := pkg.TypesInfo.TypeOf(typeArgs[0])
+			argNamed, ok := argType.(*types.Named)
+			if !ok || argNamed.Obj().Pkg() != pkg.Types {
+				return true
+			}
+			iface, ok := argType.Underlying().(*types.Interface)
+			if !ok {
+				return true
+			}
+
+			ifaces[types.TypeString(argType, types.RelativeTo(pkg.Types))] = iface
+
+			return true
+		})
+	}
+
+	return ifaces
+}
+
+// findRegistrations walks pkg's syntax trees for type declarations with a
+// //poly:register directive comment immediately above them, resolving each
+// to the value or pointer form that actually implements the interfaces in
+// ifaces (preferring the value form if it implements at least one of them).
+func findRegistrations(pkg *packages.Package, ifaces map[string]*types.Interface) []registration {
+	var out []registration
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+
+				doc := typeSpec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				discriminator, ok := directiveDiscriminator(doc)
+				if !ok {
+					continue
+				}
+
+				obj := pkg.TypesInfo.Defs[typeSpec.Name]
+				if obj == nil {
+					continue
+				}
+
+				name := typeSpec.Name.Name
+				typ := obj.Type()
+				pointer := onlyPointerImplements(typ, ifaces)
+
+				reg := registration{
+					discriminator: discriminator,
+					typ:           typ,
+					// newExpr always constructs a pointer, matching the
+					// reflect.New used by the reflection-based path, so the
+					// decoded value is addressable regardless of which form
+					// implements the interface.
+					newExpr: "&" + name + "{}",
+				}
+				if pointer {
+					reg.typeExpr = "*" + name
+				} else {
+					reg.typeExpr = name
+				}
+
+				out = append(out, reg)
+			}
+		}
+	}
+
+	return out
+}
+
+// onlyPointerImplements reports whether typ only satisfies any of ifaces
+// through its pointer form, meaning that's the only form that can ever be
+// held as a poly.T[P].Value for one of them.
+func onlyPointerImplements(typ types.Type, ifaces map[string]*types.Interface) bool {
+	implementsValue := false
+	implementsPointer := false
+	for _, iface := range ifaces {
+		if types.Implements(typ, iface) {
+			implementsValue = true
+		}
+		if types.Implements(types.NewPointer(typ), iface) {
+			implementsPointer = true
+		}
+	}
+	return !implementsValue && implementsPointer
+}
+
+// directiveDiscriminator extracts the discriminator from a //poly:register
+// "disc" comment in doc, if present.
+func directiveDiscriminator(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, line := range doc.List {
+		if !strings.HasPrefix(line.Text, registerDirectivePrefix) {
+			continue
+		}
+		quoted := strings.TrimSpace(strings.TrimPrefix(line.Text, registerDirectivePrefix))
+		discriminator, err := strconv.Unquote(quoted)
+		if err != nil {
+			continue
+		}
+		return discriminator, true
+	}
+	return "", false
+}
+
+var generateTemplate = template.Must(template.New("polygen").Parse(`// Code generated by polygen. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/clickermonkey/poly"
+
+func init() {
+{{- range .Registrations}}
+	poly.Register[{{.TypeExpr}}]({{.Discriminator | printf "%q"}})
+{{- end}}
+{{range .Dispatches}}
+	poly.RegisterGenerated[{{.Name}}](
+		func(v {{.Name}}) (string, bool) {
+			switch v.(type) {
+{{- range .Registrations}}
+			case {{.TypeExpr}}:
+				return {{.Discriminator | printf "%q"}}, true
+{{- end}}
+			}
+			return "", false
+		},
+		func(discriminator string) ({{.Name}}, bool) {
+			switch discriminator {
+{{- range .Registrations}}
+			case {{.Discriminator | printf "%q"}}:
+				return {{.NewExpr}}, true
+{{- end}}
+			}
+			return nil, false
+		},
+	)
+{{- end}}
+}
+`))
+
+type templateRegistration struct {
+	TypeExpr      string
+	NewExpr       string
+	Discriminator string
+}
+
+type templateDispatch struct {
+	Name          string
+	Registrations []templateRegistration
+}
+
+func render(pkgName string, registrations []registration, dispatches []interfaceDispatch) ([]byte, error) {
+	data := struct {
+		Package       string
+		Registrations []templateRegistration
+		Dispatches    []templateDispatch
+	}{
+		Package: pkgName,
+	}
+
+	for _, reg := range registrations {
+		data.Registrations = append(data.Registrations, templateRegistration{
+			TypeExpr:      reg.typeExpr,
+			NewExpr:       reg.newExpr,
+			Discriminator: reg.discriminator,
+		})
+	}
+
+	for _, dispatch := range dispatches {
+		td := templateDispatch{Name: dispatch.name}
+		for _, reg := range dispatch.registrations {
+			td.Registrations = append(td.Registrations, templateRegistration{
+				TypeExpr:      reg.typeExpr,
+				NewExpr:       reg.newExpr,
+				Discriminator: reg.discriminator,
+			})
+		}
+		data.Dispatches = append(data.Dispatches, td)
+	}
+
+	var buf bytes.Buffer
+	if err := generateTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w\n%s", err, buf.String())
+	}
+
+	return formatted, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate("testdata/fixture")
+	assert.NoError(t, err)
+
+	out := string(src)
+	assert.Contains(t, out, `poly.Register[EmailJob]("email")`)
+	assert.Contains(t, out, `poly.Register[*StateJob]("state")`)
+	assert.Contains(t, out, `poly.RegisterGenerated[Job](`)
+	assert.Contains(t, out, `case EmailJob:`)
+	assert.Contains(t, out, `case *StateJob:`)
+	assert.Contains(t, out, `case "email":`)
+	assert.Contains(t, out, `return &EmailJob{}, true`)
+	assert.Contains(t, out, `case "state":`)
+	assert.Contains(t, out, `return &StateJob{}, true`)
+
+	// Generate runs its output through go/format, so the NoError above means
+	// the file parsed as Go; it must also start with the generated-code header.
+	assert.True(t, strings.HasPrefix(out, "// Code generated by polygen. DO NOT EDIT."))
+}
+
+func TestGenerate_NoPackage(t *testing.T) {
+	_, err := Generate("testdata/does-not-exist")
+	assert.Error(t, err)
+}
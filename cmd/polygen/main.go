@@ -0,0 +1,52 @@
+// Command polygen scans a Go package for poly.T[P] usages and
+// //poly:register directives, then emits an init() that wires
+// reflection-free discriminator dispatch for every interface it found,
+// via poly.RegisterGenerated, alongside the usual poly.Register calls.
+//
+// This speeds up resolving a discriminator to/from its registered type
+// (replacing a reflect-based map lookup with a generated switch); it does
+// not generate a reflection-free codec for the value itself, which still
+// round-trips through encoding/json/yaml.v3 as usual. See Generate's doc
+// comment for what a full codec generator would additionally need.
+//
+// Usage:
+//
+//	polygen [-dir pkg/dir] [-out file.go]
+//
+// It's meant to be driven by a go:generate directive in the package it
+// scans, e.g.:
+//
+//	//go:generate go run github.com/clickermonkey/poly/cmd/polygen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package to scan")
+	out := flag.String("out", "poly_generated.go", "generated file name, relative to -dir")
+	flag.Parse()
+
+	if err := run(*dir, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "polygen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, out string) error {
+	src, err := Generate(dir)
+	if err != nil {
+		return err
+	}
+
+	outPath := out
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(dir, out)
+	}
+
+	return os.WriteFile(outPath, src, 0o644)
+}
@@ -0,0 +1,32 @@
+// Package fixture is a small testdata package used to exercise Generate
+// against real poly.T[P] usages and //poly:register directives.
+package fixture
+
+import "github.com/clickermonkey/poly"
+
+type Job interface {
+	Do() string
+}
+
+//poly:register "email"
+type EmailJob struct {
+	Message string `json:"message"`
+}
+
+func (e EmailJob) Do() string {
+	return e.Message
+}
+
+//poly:register "state"
+type StateJob struct {
+	Done int `json:"done"`
+}
+
+func (s *StateJob) Do() string {
+	s.Done++
+	return "done"
+}
+
+type HasJob struct {
+	Job poly.T[Job] `json:"job"`
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const polyImportPath = "github.com/clickermonkey/poly"
+
+// Options configures Generate.
+type Options struct {
+	// IfacePkg and IfaceName name the interface to scan for, e.g.
+	// "github.com/you/app/job" and "Job".
+	IfacePkg, IfaceName string
+	// Patterns are package patterns to scan, resolved the way `go build`
+	// resolves them (e.g. "./...").
+	Patterns []string
+	// Dir is the directory patterns are resolved relative to.
+	Dir string
+	// Package is the package name of the generated file.
+	Package string
+}
+
+// An implementation of the scanned interface found in some package. The
+// import alias isn't decided here: two distinct packages can share the same
+// base name (e.g. ".../a/model" and ".../b/model"), so render assigns
+// aliases once it can see every package path that needs one.
+type implementation struct {
+	pkgPath       string
+	pkgName       string // pkg.Name, the default import alias candidate
+	name          string
+	pointer       bool   // true if only *name implements the interface
+	discriminator string // the unqualified type name, per poly.DefaultNamer
+}
+
+// Generate scans every package matched by opts.Patterns for concrete types
+// implementing the interface named by opts.IfacePkg/opts.IfaceName, and
+// returns the source of a "DO NOT EDIT" file, in package opts.Package, whose
+// init() registers every one found with poly.Register.
+//
+// A type is registered under its unqualified name, matching
+// poly.DefaultNamer; Generate fails, without rendering anything, if two
+// matched types across different packages would collide on that name. It's
+// the build-time counterpart to poly.Declare paired with poly.AutoRegister.
+func Generate(opts Options) ([]byte, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir:  opts.Dir,
+	}
+	pkgs, err := packages.Load(cfg, append([]string{opts.IfacePkg}, opts.Patterns...)...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("packages matched by %v have errors", opts.Patterns)
+	}
+
+	iface, err := findInterface(pkgs, opts.IfacePkg, opts.IfaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var impls []implementation
+	seen := map[string]bool{}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == opts.IfacePkg {
+			continue
+		}
+		if seen[pkg.PkgPath] {
+			continue
+		}
+		seen[pkg.PkgPath] = true
+
+		impls = append(impls, findImplementations(pkg, iface)...)
+	}
+
+	sort.Slice(impls, func(i, j int) bool {
+		if impls[i].discriminator != impls[j].discriminator {
+			return impls[i].discriminator < impls[j].discriminator
+		}
+		return impls[i].pkgPath < impls[j].pkgPath
+	})
+
+	for i := 1; i < len(impls); i++ {
+		if impls[i].discriminator == impls[i-1].discriminator {
+			return nil, fmt.Errorf("discriminator collision: %q for both %s.%s and %s.%s",
+				impls[i].discriminator,
+				impls[i-1].pkgPath, impls[i-1].name,
+				impls[i].pkgPath, impls[i].name)
+		}
+	}
+
+	return render(opts.Package, impls)
+}
+
+// findInterface locates the *types.Interface for ifacePkg.ifaceName among
+// pkgs or their imports.
+func findInterface(pkgs []*packages.Package, ifacePkg, ifaceName string) (*types.Interface, error) {
+	var found *types.Package
+	seen := map[*packages.Package]bool{}
+	var visit func(pkg *packages.Package)
+	visit = func(pkg *packages.Package) {
+		if pkg == nil || seen[pkg] || found != nil {
+			return
+		}
+		seen[pkg] = true
+		if pkg.PkgPath == ifacePkg {
+			found = pkg.Types
+			return
+		}
+		for _, imp := range pkg.Imports {
+			visit(imp)
+		}
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("package %s not found among scanned packages or their imports", ifacePkg)
+	}
+
+	obj := found.Scope().Lookup(ifaceName)
+	if obj == nil {
+		return nil, fmt.Errorf("%s not found in package %s", ifaceName, ifacePkg)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not an interface", ifacePkg, ifaceName)
+	}
+	return iface, nil
+}
+
+// findImplementations returns every exported, concrete, non-interface type
+// pkg defines that implements iface, by value or by pointer.
+func findImplementations(pkg *packages.Package, iface *types.Interface) []implementation {
+	var out []implementation
+
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() || obj.IsAlias() {
+			continue
+		}
+
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if _, isIface := named.Underlying().(*types.Interface); isIface {
+			continue
+		}
+
+		implementsValue := types.Implements(named, iface)
+		implementsPointer := types.Implements(types.NewPointer(named), iface)
+		if !implementsValue && !implementsPointer {
+			continue
+		}
+
+		out = append(out, implementation{
+			pkgPath:       pkg.PkgPath,
+			pkgName:       pkg.Name,
+			name:          name,
+			pointer:       !implementsValue && implementsPointer,
+			discriminator: name,
+		})
+	}
+
+	return out
+}
+
+var generateTemplate = template.Must(template.New("polyscan").Parse(`// Code generated by polyscan. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/clickermonkey/poly"
+{{range .Imports}}
+	{{.Alias}} "{{.Path}}"
+{{- end}}
+)
+
+func init() {
+{{- range .Implementations}}
+	poly.Register[{{.TypeExpr}}]({{.Discriminator | printf "%q"}})
+{{- end}}
+}
+`))
+
+type templateImport struct {
+	Alias, Path string
+}
+
+type templateImplementation struct {
+	TypeExpr      string
+	Discriminator string
+}
+
+// assignAliases picks an import alias per distinct package path among
+// impls, defaulting to the package's own name and disambiguating
+// collisions (two different packages sharing a base name, e.g.
+// ".../a/model" and ".../b/model") by appending an increasing suffix to
+// whichever package path sorts later. It errors if a package path still
+// can't be given a unique alias, mirroring the discriminator collision
+// check in Generate.
+func assignAliases(impls []implementation) (map[string]string, error) {
+	pkgNames := map[string]string{}
+	var pkgPaths []string
+	for _, impl := range impls {
+		if _, ok := pkgNames[impl.pkgPath]; !ok {
+			pkgNames[impl.pkgPath] = impl.pkgName
+			pkgPaths = append(pkgPaths, impl.pkgPath)
+		}
+	}
+	sort.Strings(pkgPaths)
+
+	used := map[string]bool{"poly": true}
+	aliases := make(map[string]string, len(pkgPaths))
+	for _, pkgPath := range pkgPaths {
+		name := pkgNames[pkgPath]
+		alias := name
+		for n := 2; used[alias]; n++ {
+			if n > len(pkgPaths)+1 {
+				return nil, fmt.Errorf("import alias collision: could not find a unique alias for %s (base name %q)", pkgPath, name)
+			}
+			alias = fmt.Sprintf("%s%d", name, n)
+		}
+		used[alias] = true
+		aliases[pkgPath] = alias
+	}
+
+	return aliases, nil
+}
+
+func render(pkgName string, impls []implementation) ([]byte, error) {
+	aliases, err := assignAliases(impls)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		Package         string
+		Imports         []templateImport
+		Implementations []templateImplementation
+	}{
+		Package: pkgName,
+	}
+
+	seen := map[string]bool{}
+	for _, impl := range impls {
+		alias := aliases[impl.pkgPath]
+		if !seen[impl.pkgPath] {
+			seen[impl.pkgPath] = true
+			data.Imports = append(data.Imports, templateImport{Alias: alias, Path: impl.pkgPath})
+		}
+
+		typeExpr := alias + "." + impl.name
+		if impl.pointer {
+			typeExpr = "*" + typeExpr
+		}
+		data.Implementations = append(data.Implementations, templateImplementation{
+			TypeExpr:      typeExpr,
+			Discriminator: impl.discriminator,
+		})
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Path < data.Imports[j].Path })
+
+	var buf bytes.Buffer
+	if err := generateTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w\n%s", err, buf.String())
+	}
+
+	return formatted, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(Options{
+		IfacePkg:  "github.com/clickermonkey/poly/internal/autoregister/job",
+		IfaceName: "Job",
+		Patterns:  []string{"../../internal/autoregister/..."},
+		Package:   "register",
+	})
+	assert.NoError(t, err)
+
+	got := string(src)
+	assert.Contains(t, got, "package register")
+	assert.Contains(t, got, `email "github.com/clickermonkey/poly/internal/autoregister/email"`)
+	assert.Contains(t, got, `save "github.com/clickermonkey/poly/internal/autoregister/save"`)
+	assert.Contains(t, got, `state "github.com/clickermonkey/poly/internal/autoregister/state"`)
+	assert.Contains(t, got, `poly.Register[email.EmailJob]("EmailJob")`)
+	assert.Contains(t, got, `poly.Register[save.SaveJob]("SaveJob")`)
+	assert.Contains(t, got, `poly.Register[*state.StateJob]("StateJob")`)
+
+	// job itself only declares the interface, it has no implementations.
+	assert.False(t, strings.Contains(got, `"github.com/clickermonkey/poly/internal/autoregister/job"`))
+}
+
+// TestGenerate_AliasCollision covers two scanned packages that share a base
+// name ("model"), which would otherwise produce two colliding "model" import
+// lines in the generated file.
+func TestGenerate_AliasCollision(t *testing.T) {
+	src, err := Generate(Options{
+		IfacePkg:  "github.com/clickermonkey/poly/cmd/polyscan/testdata/aliastest/iface",
+		IfaceName: "Job",
+		Patterns:  []string{"./testdata/aliastest/..."},
+		Package:   "register",
+	})
+	assert.NoError(t, err)
+
+	got := string(src)
+	assert.Contains(t, got, `model "github.com/clickermonkey/poly/cmd/polyscan/testdata/aliastest/a/model"`)
+	assert.Contains(t, got, `model2 "github.com/clickermonkey/poly/cmd/polyscan/testdata/aliastest/b/model"`)
+	assert.Contains(t, got, `poly.Register[model.EmailJob]("EmailJob")`)
+	assert.Contains(t, got, `poly.Register[model2.SaveJob]("SaveJob")`)
+}
+
+func TestGenerate_BadInterface(t *testing.T) {
+	_, err := Generate(Options{
+		IfacePkg:  "github.com/clickermonkey/poly/internal/autoregister/job",
+		IfaceName: "NotAThing",
+		Patterns:  []string{"../../internal/autoregister/..."},
+		Package:   "register",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+// Command polyscan scans a whole module for concrete types implementing a
+// given interface and emits an init() that poly.Registers every one it
+// finds, as a build-time alternative to calling poly.Declare from each
+// implementation's own init() and poly.AutoRegister at runtime.
+//
+// Usage:
+//
+//	polyscan -iface github.com/you/app/job.Job -pkg register -out register/job_generated.go ./...
+//
+// -iface names the interface to scan for, as "importpath.Name". The package
+// patterns (./... by default) are resolved the same way `go build` resolves
+// them; every exported, non-interface type any of them defines that
+// implements the interface (by value or by pointer) is registered under its
+// unqualified type name, mirroring poly.DefaultNamer. polyscan fails,
+// writing nothing, if two matched types would collide on that name.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	iface := flag.String("iface", "", `interface to scan for, as "importpath.Name" (required)`)
+	dir := flag.String("dir", ".", "directory to resolve package patterns and the output path from")
+	pkg := flag.String("pkg", "", "package name for the generated file (required)")
+	out := flag.String("out", "poly_generated.go", "generated file name, relative to -dir")
+	flag.Parse()
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	if err := run(*iface, *pkg, *dir, *out, patterns); err != nil {
+		fmt.Fprintln(os.Stderr, "polyscan:", err)
+		os.Exit(1)
+	}
+}
+
+func run(iface, pkgName, dir, out string, patterns []string) error {
+	if pkgName == "" {
+		return fmt.Errorf("-pkg is required")
+	}
+
+	lastDot := strings.LastIndex(iface, ".")
+	if lastDot < 0 {
+		return fmt.Errorf(`-iface must be "importpath.Name", got %q`, iface)
+	}
+	ifacePkg, ifaceName := iface[:lastDot], iface[lastDot+1:]
+	if ifacePkg == "" || ifaceName == "" {
+		return fmt.Errorf(`-iface must be "importpath.Name", got %q`, iface)
+	}
+
+	src, err := Generate(Options{
+		IfacePkg:  ifacePkg,
+		IfaceName: ifaceName,
+		Patterns:  patterns,
+		Dir:       dir,
+		Package:   pkgName,
+	})
+	if err != nil {
+		return err
+	}
+
+	outPath := out
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(dir, out)
+	}
+
+	return os.WriteFile(outPath, src, 0o644)
+}
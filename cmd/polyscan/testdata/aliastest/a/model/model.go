@@ -0,0 +1,11 @@
+// Package model is testdata deliberately sharing its base name with
+// testdata/aliastest/b/model.
+package model
+
+type EmailJob struct {
+	Message string `json:"message"`
+}
+
+func (e EmailJob) Do() string {
+	return e.Message
+}
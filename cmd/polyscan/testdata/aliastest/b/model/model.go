@@ -0,0 +1,11 @@
+// Package model is testdata deliberately sharing its base name with
+// testdata/aliastest/a/model.
+package model
+
+type SaveJob struct {
+	Path string `json:"path"`
+}
+
+func (s SaveJob) Do() string {
+	return "saving " + s.Path
+}
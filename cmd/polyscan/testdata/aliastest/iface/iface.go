@@ -0,0 +1,8 @@
+// Package iface is testdata exercising polyscan against two implementation
+// packages that share a base name ("model"), to make sure the generated
+// file doesn't end up with two colliding import aliases.
+package iface
+
+type Job interface {
+	Do() string
+}
@@ -0,0 +1,72 @@
+package poly
+
+import "fmt"
+
+// A Codec can marshal/unmarshal arbitrary Go values to/from some wire
+// format's bytes using that format's own top-level functions, e.g.
+// cbor.Marshal/cbor.Unmarshal or msgpack.Marshal/msgpack.Unmarshal. It lets
+// a third-party format without its own T[P]-recognized Marshaler interface
+// (like encoding/xml's xml.Marshaler, fxamacker/cbor's cbor.Marshaler, or
+// vmihailenco/msgpack's msgpack.CustomEncoder, all of which T[P] implements
+// directly in codec_xml.go/codec_cbor.go/codec_msgpack.go) still encode and
+// decode T[P] via MarshalWith/UnmarshalWith.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// MarshalWith encodes d as a `[discriminator, value]` tuple using codec,
+// mirroring MarshalJSON's default EncodingTuple shape for formats that don't
+// have a Marshaler hook of their own recognized by T[P].
+func MarshalWith[P any](d T[P], codec Codec) ([]byte, error) {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return nil, err
+	}
+	if discriminator == "" {
+		return codec.Marshal([]any{})
+	}
+	return codec.Marshal([2]any{discriminator, value})
+}
+
+// UnmarshalWith decodes data produced by MarshalWith (or any `[discriminator,
+// value]` 2-element tuple codec supports) into d using codec.
+func UnmarshalWith[P any](d *T[P], data []byte, codec Codec) error {
+	var items []any
+	if err := codec.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) != 2 {
+		return fmt.Errorf("%w: expected a 2-element array, got %d", ErrInvalidJSON, len(items))
+	}
+
+	discriminator, ok := items[0].(string)
+	if !ok {
+		return fmt.Errorf("%w: expected string but got %v", ErrInvalidJSON, items[0])
+	}
+
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
+	}
+
+	// codec.Unmarshal already decoded items[1] generically (e.g. into a
+	// map[string]any); round-trip it back through codec to decode it again,
+	// this time into the concrete discriminated value. This costs an extra
+	// encode/decode pass, but it's the only way to recover per-element bytes
+	// from a Codec that doesn't expose a raw-message type of its own.
+	valueB, err := codec.Marshal(items[1])
+	if err != nil {
+		return err
+	}
+	if err := codec.Unmarshal(valueB, &discriminated); err != nil {
+		return err
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package poly
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+var (
+	_ cbor.Marshaler   = T[any]{}
+	_ cbor.Unmarshaler = &T[any]{}
+)
+
+// MarshalCBOR encodes d as a `[discriminator, value]` CBOR array, mirroring
+// MarshalJSON's default EncodingTuple shape.
+func (d T[P]) MarshalCBOR() ([]byte, error) {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return nil, err
+	}
+	if discriminator == "" {
+		return cbor.Marshal([]any{})
+	}
+	return cbor.Marshal([2]any{discriminator, value})
+}
+
+// UnmarshalCBOR decodes data produced by MarshalCBOR.
+func (d *T[P]) UnmarshalCBOR(data []byte) error {
+	var items []cbor.RawMessage
+	if err := cbor.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) != 2 {
+		return fmt.Errorf("%w: expected a 2-element array, got %d", ErrInvalidJSON, len(items))
+	}
+
+	var discriminator string
+	if err := cbor.Unmarshal(items[0], &discriminator); err != nil {
+		return err
+	}
+
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
+	}
+	if err := cbor.Unmarshal(items[1], &discriminated); err != nil {
+		return err
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package poly
+
+import (
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	_ msgpack.CustomEncoder = T[any]{}
+	_ msgpack.CustomDecoder = &T[any]{}
+)
+
+// EncodeMsgpack encodes d as a `[discriminator, value]` MessagePack array,
+// mirroring MarshalJSON's default EncodingTuple shape.
+func (d T[P]) EncodeMsgpack(enc *msgpack.Encoder) error {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return err
+	}
+	if discriminator == "" {
+		return enc.EncodeArrayLen(0)
+	}
+
+	if err := enc.EncodeArrayLen(2); err != nil {
+		return err
+	}
+	if err := enc.EncodeString(discriminator); err != nil {
+		return err
+	}
+	return enc.Encode(value)
+}
+
+// DecodeMsgpack decodes data produced by EncodeMsgpack.
+func (d *T[P]) DecodeMsgpack(dec *msgpack.Decoder) error {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	if n != 2 {
+		return fmt.Errorf("%w: expected a 2-element array, got %d", ErrInvalidJSON, n)
+	}
+
+	discriminator, err := dec.DecodeString()
+	if err != nil {
+		return err
+	}
+
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(&discriminated); err != nil {
+		return err
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package poly_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/clickermonkey/poly"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type HasJobXML struct {
+	Job poly.T[Job] `xml:"job"`
+}
+
+func TestCodecs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setup       func()
+		encode      any
+		encodedXML  string
+		decode      func() any
+		decodedTest func(decoded any, t *testing.T)
+	}{
+		{
+			name: "simple specified value",
+			setup: func() {
+				poly.Register[SaveJob]("save")
+				poly.Register[EmailJob]("email")
+			},
+			encode:     HasJobXML{Job: poly.C[Job](EmailJob{Message: "Hello World!"})},
+			encodedXML: `<HasJobXML><job type="email"><Message>Hello World!</Message></job></HasJobXML>`,
+			decode:     func() any { return &HasJobXML{} },
+			decodedTest: func(decoded any, t *testing.T) {
+				hj := decoded.(*HasJobXML)
+				assert.Equal(t, "Hello World!", hj.Job.Value.Do())
+			},
+		},
+		{
+			name: "pointer value",
+			setup: func() {
+				poly.Register[*StateJob]("state")
+			},
+			encode:     HasJobXML{Job: poly.C[Job](&StateJob{Done: 1})},
+			encodedXML: `<HasJobXML><job type="state"><Done>1</Done></job></HasJobXML>`,
+			decode:     func() any { return &HasJobXML{} },
+			decodedTest: func(decoded any, t *testing.T) {
+				hj := decoded.(*HasJobXML)
+				assert.Equal(t, "Do() #2", hj.Job.Value.Do())
+			},
+		},
+		{
+			name: "no value",
+			setup: func() {
+				poly.Register[EmailJob]("email")
+			},
+			encode:     HasJobXML{},
+			encodedXML: `<HasJobXML><job></job></HasJobXML>`,
+			decode:     func() any { return &HasJobXML{} },
+			decodedTest: func(decoded any, t *testing.T) {
+				hj := decoded.(*HasJobXML)
+				assert.Nil(t, hj.Job.Value)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			poly.Reset()
+
+			if testCase.setup != nil {
+				testCase.setup()
+			}
+
+			actualXML, err := xml.Marshal(testCase.encode)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.encodedXML, string(actualXML), "xml mismatch")
+
+			decodedXML := testCase.decode()
+			assert.NoError(t, xml.Unmarshal(actualXML, decodedXML))
+			testCase.decodedTest(decodedXML, t)
+
+			actualCBOR, err := cbor.Marshal(testCase.encode)
+			assert.NoError(t, err)
+
+			decodedCBOR := testCase.decode()
+			assert.NoError(t, cbor.Unmarshal(actualCBOR, decodedCBOR))
+			testCase.decodedTest(decodedCBOR, t)
+
+			actualMsgpack, err := msgpack.Marshal(testCase.encode)
+			assert.NoError(t, err)
+
+			decodedMsgpack := testCase.decode()
+			assert.NoError(t, msgpack.Unmarshal(actualMsgpack, decodedMsgpack))
+			testCase.decodedTest(decodedMsgpack, t)
+		})
+	}
+}
+
+func TestCodecs_CodecInterface(t *testing.T) {
+	poly.Reset()
+	poly.Register[EmailJob]("email")
+
+	value := poly.C[Job](EmailJob{Message: "hi"})
+
+	encoded, err := poly.MarshalWith(value, jsonCodec{})
+	assert.NoError(t, err)
+	assert.Equal(t, `["email",{"message":"hi"}]`, string(encoded))
+
+	decoded := poly.T[Job]{}
+	assert.NoError(t, poly.UnmarshalWith(&decoded, encoded, jsonCodec{}))
+	assert.Equal(t, "hi", decoded.Value.Do())
+}
+
+// jsonCodec adapts encoding/json to poly.Codec, as a stand-in for a
+// third-party format that only exposes Marshal/Unmarshal free functions
+// matching that shape.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,59 @@
+package poly
+
+import (
+	"encoding/xml"
+	"reflect"
+)
+
+var (
+	_ xml.Marshaler   = T[any]{}
+	_ xml.Unmarshaler = &T[any]{}
+)
+
+// MarshalXML encodes d as its element with the discriminator set as a
+// "type" attribute (or whatever key SetDiscriminatorKey/SetDiscriminatorKeyFor
+// overrides it to), e.g. `<job type="email"><message>hi</message></job>`.
+func (d T[P]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return err
+	}
+	if discriminator == "" {
+		return e.EncodeElement(struct{}{}, start)
+	}
+
+	typ := reflect.TypeFor[P]()
+	key := d.registry().discriminatorKeyFor(typ, EncodingWrappedTag)
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key}, Value: discriminator})
+
+	return e.EncodeElement(value, start)
+}
+
+// UnmarshalXML decodes an element produced by MarshalXML.
+func (d *T[P]) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	typ := reflect.TypeFor[P]()
+	key := d.registry().discriminatorKeyFor(typ, EncodingWrappedTag)
+
+	var discriminator string
+	for _, attr := range start.Attr {
+		if attr.Name.Local == key {
+			discriminator = attr.Value
+			break
+		}
+	}
+	if discriminator == "" {
+		return dec.Skip()
+	}
+
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
+	}
+	if err := dec.DecodeElement(&discriminated, &start); err != nil {
+		return err
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
@@ -0,0 +1,20 @@
+// Package email provides a job.Job implementation that declares
+// itself for poly.AutoRegister at init time.
+package email
+
+import (
+	"github.com/clickermonkey/poly"
+)
+
+func init() {
+	poly.Declare[EmailJob]()
+}
+
+// EmailJob sends a message.
+type EmailJob struct {
+	Message string `json:"message"`
+}
+
+func (e EmailJob) Do() string {
+	return e.Message
+}
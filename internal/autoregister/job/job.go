@@ -0,0 +1,10 @@
+// Package job defines the shared interface AutoRegister's implementation
+// packages (email, save, state) declare themselves against, and that the
+// package poly_test tests scan for.
+package job
+
+// Job is the interface implementation packages under internal/autoregister
+// register themselves against via poly.Declare.
+type Job interface {
+	Do() string
+}
@@ -0,0 +1,20 @@
+// Package save provides a job.Job implementation that declares
+// itself for poly.AutoRegister at init time.
+package save
+
+import (
+	"github.com/clickermonkey/poly"
+)
+
+func init() {
+	poly.Declare[SaveJob]()
+}
+
+// SaveJob persists something.
+type SaveJob struct {
+	Path string `json:"path"`
+}
+
+func (s SaveJob) Do() string {
+	return "saving " + s.Path
+}
@@ -0,0 +1,23 @@
+// Package state provides a job.Job implementation that declares
+// itself for poly.AutoRegister at init time. Its Do method has a pointer
+// receiver, exercising AutoRegister's pointer-implementation path.
+package state
+
+import (
+	"fmt"
+
+	"github.com/clickermonkey/poly"
+)
+
+func init() {
+	poly.Declare[*StateJob]()
+}
+
+// StateJob transitions to Done.
+type StateJob struct {
+	Done int `json:"done"`
+}
+
+func (s *StateJob) Do() string {
+	return fmt.Sprintf("Do() #%d", s.Done)
+}
@@ -16,62 +16,62 @@ var (
 	ErrInvalidJSON          = errors.New("invalid discriminator json")
 
 	DataNone = []byte("[]")
-
-	byType                     map[reflect.Type]string
-	byTypeSpecialized          map[reflect.Type]map[reflect.Type]string
-	byDiscriminator            map[string]reflect.Type
-	byDiscriminatorSpecialized map[reflect.Type]map[string]reflect.Type
 )
 
-func init() {
-	Reset()
-}
-
-// Clears out all registered discriminators.
-func Reset() {
-	byType = make(map[reflect.Type]string)
-	byTypeSpecialized = make(map[reflect.Type]map[reflect.Type]string)
-	byDiscriminator = make(map[string]reflect.Type)
-	byDiscriminatorSpecialized = make(map[reflect.Type]map[string]reflect.Type)
-}
-
-// Registers a discriminator for the given type. This is the fallback/general
-// discriminator. A specialized one can be set with RegisterSpecialized.
+// Registers a discriminator for the given type on DefaultRegistry. This is
+// the fallback/general discriminator. A specialized one can be set with
+// RegisterSpecialized. To register on a specific Registry use RegisterIn.
 func Register[S any](discriminator string) {
-	typ := reflect.TypeFor[S]()
-	byType[typ] = discriminator
-	byDiscriminator[discriminator] = typ
+	RegisterIn[S](DefaultRegistry, discriminator)
 }
 
-// Registers a discriminator for type S which implements interface P.
-// Type S may have other discriminators, but when the polymorphic type instance
-// uses interface P it will use this discriminator.
+// Registers a discriminator for type S which implements interface P on
+// DefaultRegistry. Type S may have other discriminators, but when the
+// polymorphic type instance uses interface P it will use this discriminator.
+// To register on a specific Registry use RegisterSpecializedIn.
 func RegisterSpecialized[P any, S any](discriminator string) {
-	typT := reflect.TypeFor[S]()
-	typS := reflect.TypeFor[P]()
-	if _, specialExists := byTypeSpecialized[typS]; !specialExists {
-		byTypeSpecialized[typS] = make(map[reflect.Type]string)
-	}
-	if _, specialExists := byDiscriminatorSpecialized[typS]; !specialExists {
-		byDiscriminatorSpecialized[typS] = make(map[string]reflect.Type)
-	}
-	byTypeSpecialized[typS][typT] = discriminator
-	byDiscriminatorSpecialized[typS][discriminator] = typT
+	RegisterSpecializedIn[P, S](DefaultRegistry, discriminator)
+}
+
+// Clears out all discriminators and encoding overrides registered on
+// DefaultRegistry.
+func Reset() {
+	DefaultRegistry.Reset()
 }
 
-// Creates a polymorphic instance for interface P.
+// Creates a polymorphic instance for interface P that resolves
+// discriminators against DefaultRegistry. To scope it to a specific
+// Registry use CIn.
 func C[P any](value P) T[P] {
 	return T[P]{Value: value}
 }
 
-// Creates a pointer to a polymorphic instance for interface P.
+// Creates a pointer to a polymorphic instance for interface P that resolves
+// discriminators against DefaultRegistry. To scope it to a specific Registry
+// use PIn.
 func P[P any](value P) *T[P] {
 	return &T[P]{Value: value}
 }
 
-// A polymorphic instance for interface P.
+// Creates a polymorphic instance for interface P that resolves
+// discriminators against r instead of DefaultRegistry.
+func CIn[P any](r *Registry, value P) T[P] {
+	return T[P]{Value: value, Registry: r}
+}
+
+// Creates a pointer to a polymorphic instance for interface P that resolves
+// discriminators against r instead of DefaultRegistry.
+func PIn[P any](r *Registry, value P) *T[P] {
+	return &T[P]{Value: value, Registry: r}
+}
+
+// A polymorphic instance for interface P. If Registry is nil, discriminators
+// are resolved against the registry of the json.Decoder/yaml.Decoder driving
+// the decode (see Registry.NewJSONDecoder/NewYAMLDecoder), falling back to
+// DefaultRegistry.
 type T[P any] struct {
-	Value P
+	Value    P
+	Registry *Registry
 }
 
 var _ json.Marshaler = T[any]{}
@@ -79,6 +79,19 @@ var _ json.Unmarshaler = &T[any]{}
 var _ yaml.Marshaler = T[any]{}
 var _ yaml.Unmarshaler = &T[any]{}
 
+// Returns the Registry this instance resolves discriminators against: an
+// explicit T[P].Registry, the registry scoped to the current decode (if
+// any), or DefaultRegistry.
+func (d T[P]) registry() *Registry {
+	if d.Registry != nil {
+		return d.Registry
+	}
+	if r, ok := contextRegistry(); ok {
+		return r
+	}
+	return DefaultRegistry
+}
+
 // Returns the discriminator for the value in this polymorphic type.
 // If no Value is defined then "" will be returned.
 func (d T[P]) Discriminator() string {
@@ -87,31 +100,34 @@ func (d T[P]) Discriminator() string {
 		return ""
 	}
 
-	valueT := reflect.TypeOf(d.Value)
-	specialT := reflect.TypeFor[P]()
-	discriminator := ""
-	if special, ok := byTypeSpecialized[specialT]; ok {
-		discriminator = special[valueT]
-	}
-	if discriminator == "" {
-		discriminator = byType[valueT]
+	typ := reflect.TypeFor[P]()
+	registry := d.registry()
+
+	// Prefer a generated, reflection-free lookup (see RegisterGeneratedIn)
+	// over the registry's reflect-based map, falling back to the latter so
+	// generated and hand-registered types can be mixed freely.
+	if discriminator, ok := registry.generatedDiscriminator(typ, d.Value); ok {
+		return discriminator
 	}
 
-	return discriminator
+	return registry.discriminatorFor(typ, reflect.TypeOf(d.Value))
 }
 
 // Returns a new *P value for the discriminator. If there is no valid value
 // for the discriminator OR it does not implement P then nil will be returned.
 func (d T[P]) Discriminated(discriminator string) (P, bool) {
-	specialP := reflect.TypeFor[P]()
-	var valueP reflect.Type
-	if special, exists := byDiscriminatorSpecialized[specialP]; exists {
-		valueP = special[discriminator]
-	}
-	if valueP == nil {
-		valueP = byDiscriminator[discriminator]
-	}
+	typ := reflect.TypeFor[P]()
+	registry := d.registry()
 	var emptyP P
+
+	if value, ok := registry.generatedNewFor(typ, discriminator); ok {
+		if valueP, ok := value.(P); ok {
+			return valueP, true
+		}
+		return emptyP, false
+	}
+
+	valueP := registry.typeForDiscriminator(typ, discriminator)
 	if valueP == nil {
 		return emptyP, false
 	}
@@ -134,91 +150,328 @@ func (d T[P]) IsZero() bool {
 	return !rv.IsValid() || rv.IsZero()
 }
 
+// discriminate resolves the discriminator and value to encode for d, shared
+// by every codec's marshal method so each only has to handle its own
+// framing. An empty discriminator with a nil error means d is zero-valued
+// and should be encoded as whatever that codec's empty-value sentinel is.
+func (d T[P]) discriminate() (discriminator string, value P, err error) {
+	if d.IsZero() {
+		return "", value, nil
+	}
+
+	discriminator = d.Discriminator()
+	if discriminator == "" {
+		return "", value, fmt.Errorf("%w: for %v of %v", ErrMissingDiscriminator, reflect.TypeOf(d.Value), reflect.TypeFor[P]())
+	}
+
+	return discriminator, d.Value, nil
+}
+
+// undiscriminate resolves discriminator to a concrete, addressable *P value
+// via d.Discriminated, shared by every codec's unmarshal method so each only
+// has to decode its own framing's raw value bytes into the result.
+func (d T[P]) undiscriminate(discriminator string) (P, error) {
+	discriminated, ok := d.Discriminated(discriminator)
+	if !ok {
+		var empty P
+		return empty, fmt.Errorf("%w: %s of %v", ErrMissingTypeFor, discriminator, reflect.TypeFor[P]())
+	}
+	return discriminated, nil
+}
+
+//===================================================================
+// Encoding: controls how a T[P] is shaped as JSON/YAML.
+//===================================================================
+
+// Encoding controls how a T[P] value is encoded.
+type Encoding int
+
+const (
+	// EncodingTuple encodes as `["discriminator", value]`. This is the
+	// default and preserves the original poly encoding.
+	EncodingTuple Encoding = iota
+	// EncodingInlineTag encodes as `{"<key>": "discriminator", ...value fields}`,
+	// with the discriminator inlined alongside the value's own fields. This
+	// matches protobuf jsonpb's `@type` convention for `Any`.
+	EncodingInlineTag
+	// EncodingWrappedTag encodes as `{"<key>": "discriminator", "value": {...}}`,
+	// with the value nested under a "value" property. This matches protobuf's
+	// `type_url`/`value` convention for `Any`.
+	EncodingWrappedTag
+)
+
+const (
+	defaultInlineKey  = "@type"
+	defaultWrappedKey = "type"
+	wrappedValueKey   = "value"
+)
+
+// Sets the default encoding used by all polymorphic types resolved against
+// DefaultRegistry that don't have a per-type override set with
+// SetEncodingFor. To set this on a specific Registry use (*Registry).SetEncoding.
+func SetEncoding(encoding Encoding) {
+	DefaultRegistry.SetEncoding(encoding)
+}
+
+// Sets the default discriminator key used by EncodingInlineTag and
+// EncodingWrappedTag on DefaultRegistry for all polymorphic types that don't
+// have a per-type override set with SetDiscriminatorKeyFor. Passing ""
+// restores the mode-specific default ("@type" for inline, "type" for
+// wrapped). To set this on a specific Registry use (*Registry).SetDiscriminatorKey.
+func SetDiscriminatorKey(key string) {
+	DefaultRegistry.SetDiscriminatorKey(key)
+}
+
+// Overrides the encoding used for T[P] on DefaultRegistry specifically,
+// regardless of the global default set with SetEncoding. To set this on a
+// specific Registry use SetEncodingForIn.
+func SetEncodingFor[P any](encoding Encoding) {
+	SetEncodingForIn[P](DefaultRegistry, encoding)
+}
+
+// Overrides the discriminator key used for T[P] on DefaultRegistry
+// specifically, regardless of the global default set with
+// SetDiscriminatorKey. To set this on a specific Registry use
+// SetDiscriminatorKeyForIn.
+func SetDiscriminatorKeyFor[P any](key string) {
+	SetDiscriminatorKeyForIn[P](DefaultRegistry, key)
+}
+
 //===================================================================
-// JSON: stored in the format of `['discriminator', value]`
+// JSON
 //===================================================================
 
 func (d T[P]) MarshalJSON() ([]byte, error) {
-	if d.IsZero() {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return nil, err
+	}
+	if discriminator == "" {
 		return DataNone, nil
 	}
 
-	discriminator := d.Discriminator()
-	if discriminator == "" {
-		return nil, fmt.Errorf("%w: for %v of %v", ErrMissingDiscriminator, reflect.TypeOf(d.Value), reflect.TypeFor[P]())
+	typ := reflect.TypeFor[P]()
+	registry := d.registry()
+	encoding := registry.encodingFor(typ)
+
+	switch encoding {
+	case EncodingInlineTag:
+		return marshalInlineTagJSON(registry.discriminatorKeyFor(typ, encoding), discriminator, value)
+	case EncodingWrappedTag:
+		return json.Marshal(map[string]any{
+			registry.discriminatorKeyFor(typ, encoding): discriminator,
+			wrappedValueKey: value,
+		})
+	default:
+		return json.Marshal([]any{discriminator, value})
+	}
+}
+
+// Marshals value to JSON and inlines the discriminator key/value as a
+// sibling of the value's own fields, e.g. `{"@type":"email","message":"hi"}`.
+func marshalInlineTagJSON(key, discriminator string, value any) ([]byte, error) {
+	valueB, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(valueB)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("%w: inline tag encoding requires an object value, got %s", ErrInvalidJSON, trimmed)
 	}
 
-	return json.Marshal([]any{
-		discriminator,
-		d.Value,
-	})
+	keyB, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	tagB, err := json.Marshal(discriminator)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(nil)
+	out.WriteByte('{')
+	out.Write(keyB)
+	out.WriteByte(':')
+	out.Write(tagB)
+	if len(trimmed) > len("{}") {
+		out.WriteByte(',')
+		out.Write(trimmed[1 : len(trimmed)-1])
+	}
+	out.WriteByte('}')
+
+	return out.Bytes(), nil
 }
 
 func (d *T[P]) UnmarshalJSON(b []byte) error {
-	if bytes.Equal(b, DataNone) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, DataNone) {
 		return nil
 	}
 
-	dec := json.NewDecoder(bytes.NewReader(b))
+	switch trimmed[0] {
+	case '[':
+		return d.unmarshalTupleJSON(trimmed)
+	case '{':
+		return d.unmarshalTaggedJSON(trimmed)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidJSON, trimmed)
+	}
+}
 
-	t, err := dec.Token()
+// Decodes the original `[discriminator, value]` form. Framing is scanned by
+// hand (see tokenize.go) instead of walked token-by-token through
+// json.Decoder, so locating the discriminator and the value's raw bytes
+// doesn't pay for Decoder's buffering and per-token interface boxing. The
+// value's own bytes still go through json.Unmarshal once scanJSONValue has
+// found them, since a value's concrete field layout is arbitrary (see
+// cmd/polygen's Generate doc comment for why that part isn't scanned away
+// too).
+func (d *T[P]) unmarshalTupleJSON(b []byte) error {
+	i := skipJSONSpace(b, 0)
+	if i >= len(b) || b[i] != '[' {
+		return fmt.Errorf("%w: expected '['", ErrInvalidJSON)
+	}
+	i++
+
+	i = skipJSONSpace(b, i)
+	if i >= len(b) || b[i] != '"' {
+		return fmt.Errorf("%w: expected discriminator string", ErrInvalidJSON)
+	}
+	discStart := i
+	discEnd, err := scanJSONString(b, i)
 	if err != nil {
 		return err
 	}
-	if t != json.Delim('[') {
-		return fmt.Errorf("%w: %v", ErrInvalidJSON, t)
+	var discriminator string
+	if err := json.Unmarshal(b[discStart:discEnd], &discriminator); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
 	}
 
-	t, err = dec.Token()
+	discriminated, err := d.undiscriminate(discriminator)
 	if err != nil {
 		return err
 	}
-	discriminator := ""
-	if s, ok := t.(string); ok {
-		discriminator = s
-	} else {
-		return fmt.Errorf("%w: expected string but got %v", ErrInvalidJSON, t)
+
+	i = skipJSONSpace(b, discEnd)
+	if i >= len(b) || b[i] != ',' {
+		return fmt.Errorf("%w: expected ','", ErrInvalidJSON)
 	}
 
-	discriminated, ok := d.Discriminated(discriminator)
-	if !ok {
-		return fmt.Errorf("%w: %s of %v", ErrMissingTypeFor, discriminator, reflect.TypeFor[P]())
+	valueStart := skipJSONSpace(b, i+1)
+	valueEnd, err := scanJSONValue(b, valueStart)
+	if err != nil {
+		return err
+	}
+
+	i = skipJSONSpace(b, valueEnd)
+	if i >= len(b) || b[i] != ']' {
+		return fmt.Errorf("%w: expected closing ']'", ErrInvalidJSON)
+	}
+
+	if err := json.Unmarshal(b[valueStart:valueEnd], &discriminated); err != nil {
+		return err
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
+
+// Decodes either the inline-tag or wrapped-tag object forms.
+func (d *T[P]) unmarshalTaggedJSON(b []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
 	}
 
-	valueB := b[dec.InputOffset()+1 : len(b)-1]
+	typ := reflect.TypeFor[P]()
+	_, hasInlineKey := fields[defaultInlineKey]
+	key := d.registry().taggedDiscriminatorKey(typ, hasInlineKey)
 
-	err = json.Unmarshal(valueB, &discriminated)
+	tagB, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("%w: missing %q key", ErrMissingDiscriminator, key)
+	}
+	var discriminator string
+	if err := json.Unmarshal(tagB, &discriminator); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	discriminated, err := d.undiscriminate(discriminator)
 	if err != nil {
 		return err
 	}
 
+	if valueB, ok := fields[wrappedValueKey]; ok && len(fields) == 2 {
+		if err := json.Unmarshal(valueB, &discriminated); err != nil {
+			return err
+		}
+	} else {
+		delete(fields, key)
+		inlineB, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(inlineB, &discriminated); err != nil {
+			return err
+		}
+	}
+
 	d.Value = discriminated
 
 	return nil
 }
 
 //===================================================================
-// JSON: stored in the format of:
-// property:
-//   - discriminator
-//   - value
+// YAML
 //===================================================================
 
 func (d T[P]) MarshalYAML() (any, error) {
-	// Contains no polymorphic value
-	if d.IsZero() {
+	discriminator, value, err := d.discriminate()
+	if err != nil {
+		return nil, err
+	}
+	if discriminator == "" {
 		return nil, nil
 	}
 
-	discriminator := d.Discriminator()
-	if discriminator == "" {
-		return nil, fmt.Errorf("%w: for %v of %v", ErrMissingDiscriminator, reflect.TypeOf(d.Value), reflect.TypeFor[P]())
+	typ := reflect.TypeFor[P]()
+	registry := d.registry()
+	encoding := registry.encodingFor(typ)
+
+	switch encoding {
+	case EncodingInlineTag:
+		return marshalInlineTagYAML(registry.discriminatorKeyFor(typ, encoding), discriminator, value)
+	case EncodingWrappedTag:
+		return map[string]any{
+			registry.discriminatorKeyFor(typ, encoding): discriminator,
+			wrappedValueKey: value,
+		}, nil
+	default:
+		return []any{discriminator, value}, nil
 	}
+}
+
+// Encodes value to a YAML mapping node and inlines the discriminator
+// key/value as a sibling of the value's own fields.
+func marshalInlineTagYAML(key, discriminator string, value any) (*yaml.Node, error) {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return nil, err
+	}
+	if valueNode.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: inline tag encoding requires a mapping value", ErrInvalidJSON)
+	}
+
+	content := make([]*yaml.Node, 0, len(valueNode.Content)+2)
+	content = append(content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: discriminator},
+	)
+	valueNode.Content = append(content, valueNode.Content...)
 
-	return []any{
-		discriminator,
-		d.Value,
-	}, nil
+	return valueNode, nil
 }
 
 func (d *T[P]) UnmarshalYAML(value *yaml.Node) error {
@@ -227,6 +480,16 @@ func (d *T[P]) UnmarshalYAML(value *yaml.Node) error {
 		return nil
 	}
 
+	switch value.Kind {
+	case yaml.MappingNode:
+		return d.unmarshalTaggedYAML(value)
+	default:
+		return d.unmarshalTupleYAML(value)
+	}
+}
+
+// Decodes the original `[discriminator, value]` form.
+func (d *T[P]) unmarshalTupleYAML(value *yaml.Node) error {
 	pair := [2]yaml.Node{}
 	err := value.Decode(&pair)
 	if err != nil {
@@ -238,9 +501,9 @@ func (d *T[P]) UnmarshalYAML(value *yaml.Node) error {
 		return fmt.Errorf("%w: for %v of %v", ErrMissingDiscriminator, reflect.TypeOf(d.Value), reflect.TypeFor[P]())
 	}
 
-	discriminated, ok := d.Discriminated(discriminator)
-	if !ok {
-		return fmt.Errorf("%w: %s of %v", ErrMissingTypeFor, discriminator, reflect.TypeFor[P]())
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
 	}
 	err = pair[1].Decode(discriminated)
 	if err != nil {
@@ -250,3 +513,50 @@ func (d *T[P]) UnmarshalYAML(value *yaml.Node) error {
 
 	return nil
 }
+
+// Decodes either the inline-tag or wrapped-tag mapping forms.
+func (d *T[P]) unmarshalTaggedYAML(value *yaml.Node) error {
+	fields := map[string]yaml.Node{}
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+
+	typ := reflect.TypeFor[P]()
+	_, hasInlineKey := fields[defaultInlineKey]
+	key := d.registry().taggedDiscriminatorKey(typ, hasInlineKey)
+
+	tagNode, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("%w: missing %q key", ErrMissingDiscriminator, key)
+	}
+	discriminator := tagNode.Value
+
+	discriminated, err := d.undiscriminate(discriminator)
+	if err != nil {
+		return err
+	}
+
+	if valueNode, ok := fields[wrappedValueKey]; ok && len(fields) == 2 {
+		if err := valueNode.Decode(discriminated); err != nil {
+			return err
+		}
+	} else {
+		remaining := map[string]yaml.Node{}
+		for k, v := range fields {
+			if k != key {
+				remaining[k] = v
+			}
+		}
+		remainingNode := &yaml.Node{}
+		if err := remainingNode.Encode(remaining); err != nil {
+			return err
+		}
+		if err := remainingNode.Decode(discriminated); err != nil {
+			return err
+		}
+	}
+
+	d.Value = discriminated
+
+	return nil
+}
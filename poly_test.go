@@ -46,6 +46,11 @@ type HasPointerJob struct {
 	Job *poly.T[Job] `json:"job,omitempty"`
 }
 
+type TaggedEmailJob struct {
+	EmailJob
+	Priority int `json:"priority"`
+}
+
 func TestHappy(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -247,3 +252,134 @@ func TestHappy(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodingModes(t *testing.T) {
+	t.Cleanup(func() {
+		poly.SetEncoding(poly.EncodingTuple)
+		poly.SetDiscriminatorKey("")
+	})
+
+	t.Run("inline tag", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingInlineTag)
+
+		encoded := HasJob{Job: poly.C[Job](EmailJob{Message: "Hello World!"})}
+
+		actualJSON, err := json.Marshal(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"job":{"@type":"email","message":"Hello World!"}}`, string(actualJSON))
+
+		decoded := &HasJob{}
+		assert.NoError(t, json.Unmarshal(actualJSON, decoded))
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+
+	t.Run("wrapped tag", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingWrappedTag)
+
+		encoded := HasJob{Job: poly.C[Job](EmailJob{Message: "Hello World!"})}
+
+		actualJSON, err := json.Marshal(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"job":{"type":"email","value":{"message":"Hello World!"}}}`, string(actualJSON))
+
+		decoded := &HasJob{}
+		assert.NoError(t, json.Unmarshal(actualJSON, decoded))
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+
+	t.Run("custom discriminator key", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingWrappedTag)
+		poly.SetDiscriminatorKey("kind")
+
+		encoded := HasJob{Job: poly.C[Job](EmailJob{Message: "Hello World!"})}
+
+		actualJSON, err := json.Marshal(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"job":{"kind":"email","value":{"message":"Hello World!"}}}`, string(actualJSON))
+
+		decoded := &HasJob{}
+		assert.NoError(t, json.Unmarshal(actualJSON, decoded))
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+
+	t.Run("per-type override", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncodingFor[Job](poly.EncodingInlineTag)
+		defer poly.SetEncodingFor[Job](poly.EncodingTuple)
+
+		encoded := HasJob{Job: poly.C[Job](EmailJob{Message: "Hello World!"})}
+
+		actualJSON, err := json.Marshal(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"job":{"@type":"email","message":"Hello World!"}}`, string(actualJSON))
+	})
+
+	t.Run("decodes tuple form regardless of configured encoding", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingInlineTag)
+
+		decoded := &HasJob{}
+		err := json.Unmarshal([]byte(`{"job":["email",{"message":"Hello World!"}]}`), decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+
+	t.Run("decodes wrapped form while configured for inline", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingInlineTag)
+
+		decoded := &HasJob{}
+		err := json.Unmarshal([]byte(`{"job":{"type":"email","value":{"message":"Hello World!"}}}`), decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+
+	t.Run("inline tag with embedded struct and sibling fields", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[TaggedEmailJob]("tagged-email")
+		poly.SetEncoding(poly.EncodingInlineTag)
+
+		encoded := HasJob{
+			Job: poly.C[Job](TaggedEmailJob{
+				EmailJob: EmailJob{Message: "Hello World!"},
+				Priority: 2,
+			}),
+		}
+
+		actualJSON, err := json.Marshal(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"job":{"@type":"tagged-email","message":"Hello World!","priority":2}}`, string(actualJSON))
+
+		decoded := &HasJob{}
+		assert.NoError(t, json.Unmarshal(actualJSON, decoded))
+		tagged := decoded.Job.Value.(*TaggedEmailJob)
+		assert.Equal(t, "Hello World!", tagged.Message)
+		assert.Equal(t, 2, tagged.Priority)
+	})
+
+	t.Run("yaml round trip in inline mode", func(t *testing.T) {
+		poly.Reset()
+		poly.Register[EmailJob]("email")
+		poly.SetEncoding(poly.EncodingInlineTag)
+
+		encoded := HasJob{Job: poly.C[Job](EmailJob{Message: "Hello World!"})}
+
+		yamlOut := strings.Builder{}
+		yamlEnc := yaml.NewEncoder(&yamlOut)
+		yamlEnc.SetIndent(2)
+		assert.NoError(t, yamlEnc.Encode(encoded))
+
+		decoded := &HasJob{}
+		assert.NoError(t, yaml.Unmarshal([]byte(yamlOut.String()), decoded))
+		assert.Equal(t, "Hello World!", decoded.Job.Value.Do())
+	})
+}
@@ -0,0 +1,491 @@
+package poly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Registry is an independent space of discriminator registrations and
+// encoding settings. Unlike the package-level functions, which all operate
+// on DefaultRegistry, a Registry lets unrelated packages or test suites
+// register types without stomping on each other.
+//
+// The zero value is not ready to use; create one with NewRegistry.
+type Registry struct {
+	mu sync.RWMutex
+
+	byType                     map[reflect.Type]string
+	byTypeSpecialized          map[reflect.Type]map[reflect.Type]string
+	byDiscriminator            map[string]reflect.Type
+	byDiscriminatorSpecialized map[reflect.Type]map[string]reflect.Type
+
+	encoding               Encoding
+	discriminatorKey       string
+	byTypeEncoding         map[reflect.Type]Encoding
+	byTypeDiscriminatorKey map[reflect.Type]string
+
+	generated map[reflect.Type]generatedCodec
+}
+
+// Creates a new, empty Registry.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.reset()
+	return r
+}
+
+// DefaultRegistry is the Registry used by T[P] values that don't specify
+// one explicitly, and by the top-level Register/RegisterSpecialized/C/etc.
+// package functions.
+var DefaultRegistry = NewRegistry()
+
+// Clears out all discriminators and encoding overrides registered on r.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+}
+
+// reset does the work of Reset without acquiring r.mu, for use both from
+// Reset and from NewRegistry before r is shared with any other goroutine.
+func (r *Registry) reset() {
+	r.byType = make(map[reflect.Type]string)
+	r.byTypeSpecialized = make(map[reflect.Type]map[reflect.Type]string)
+	r.byDiscriminator = make(map[string]reflect.Type)
+	r.byDiscriminatorSpecialized = make(map[reflect.Type]map[string]reflect.Type)
+
+	r.encoding = EncodingTuple
+	r.discriminatorKey = ""
+	r.byTypeEncoding = make(map[reflect.Type]Encoding)
+	r.byTypeDiscriminatorKey = make(map[reflect.Type]string)
+
+	r.generated = make(map[reflect.Type]generatedCodec)
+}
+
+// Sets the default encoding used by all polymorphic types resolved against r
+// that don't have a per-type override set with SetEncodingForIn.
+func (r *Registry) SetEncoding(encoding Encoding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encoding = encoding
+}
+
+// Sets the default discriminator key used by EncodingInlineTag and
+// EncodingWrappedTag on r for all polymorphic types that don't have a
+// per-type override set with SetDiscriminatorKeyForIn. Passing "" restores
+// the mode-specific default ("@type" for inline, "type" for wrapped).
+func (r *Registry) SetDiscriminatorKey(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discriminatorKey = key
+}
+
+// Registers a discriminator for S on r. This is the fallback/general
+// discriminator equivalent of the package-level Register, scoped to r. A
+// specialized one can be set with RegisterSpecializedIn.
+//
+// Go does not allow methods to introduce their own type parameters, so this
+// is a function taking the Registry rather than a (*Registry) method.
+func RegisterIn[S any](r *Registry, discriminator string) {
+	r.registerType(reflect.TypeFor[S](), discriminator)
+}
+
+// registerType does the general/fallback registration RegisterIn does,
+// given a reflect.Type discovered at runtime rather than a type parameter.
+// AutoRegister uses this since it can't name its discovered types as type
+// arguments.
+func (r *Registry) registerType(typ reflect.Type, discriminator string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byType[typ] = discriminator
+	r.byDiscriminator[discriminator] = typ
+}
+
+// existingType returns the type already generally registered on r under
+// discriminator, if any. AutoRegister uses this to detect a collision with
+// registrations already on r, not just within the batch it's adding.
+func (r *Registry) existingType(discriminator string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	typ, ok := r.byDiscriminator[discriminator]
+	return typ, ok
+}
+
+// existingDiscriminator returns the discriminator typ is already generally
+// registered under on r, if any. AutoRegister uses this to detect a
+// collision with registrations already on r, not just within the batch it's
+// adding.
+func (r *Registry) existingDiscriminator(typ reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	discriminator, ok := r.byType[typ]
+	return discriminator, ok
+}
+
+// Registers a discriminator for type S which implements interface P on r.
+// Type S may have other discriminators, but when the polymorphic type
+// instance uses interface P it will use this discriminator.
+func RegisterSpecializedIn[P any, S any](r *Registry, discriminator string) {
+	typT := reflect.TypeFor[S]()
+	typS := reflect.TypeFor[P]()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, specialExists := r.byTypeSpecialized[typS]; !specialExists {
+		r.byTypeSpecialized[typS] = make(map[reflect.Type]string)
+	}
+	if _, specialExists := r.byDiscriminatorSpecialized[typS]; !specialExists {
+		r.byDiscriminatorSpecialized[typS] = make(map[string]reflect.Type)
+	}
+	r.byTypeSpecialized[typS][typT] = discriminator
+	r.byDiscriminatorSpecialized[typS][discriminator] = typT
+}
+
+// A reflection-free discriminator codec for a single interface P, wired in
+// by RegisterGeneratedIn. Both funcs are type-erased to any so they can live
+// in Registry.generated, which is keyed by P's reflect.Type across every
+// interface the Registry knows about.
+type generatedCodec struct {
+	discriminatorOf func(value any) (string, bool)
+	newFor          func(discriminator string) (any, bool)
+}
+
+// Wires a reflection-free discriminator lookup for T[P] into r, as emitted
+// by cmd/polygen: discriminatorOf maps a concrete value to its
+// discriminator via a type switch, and newFor maps a discriminator back to
+// a zero-value instance via a string switch. T[P] checks this fast path
+// before falling back to the normal Register/RegisterSpecialized
+// reflection-based path, so generated and hand-registered types
+// interoperate through the same Registry.
+func RegisterGeneratedIn[P any](r *Registry, discriminatorOf func(P) (string, bool), newFor func(string) (P, bool)) {
+	typ := reflect.TypeFor[P]()
+	codec := generatedCodec{
+		discriminatorOf: func(value any) (string, bool) { return discriminatorOf(value.(P)) },
+		newFor:          func(discriminator string) (any, bool) { return newFor(discriminator) },
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generated[typ] = codec
+}
+
+// RegisterGenerated is the DefaultRegistry equivalent of RegisterGeneratedIn.
+func RegisterGenerated[P any](discriminatorOf func(P) (string, bool), newFor func(string) (P, bool)) {
+	RegisterGeneratedIn[P](DefaultRegistry, discriminatorOf, newFor)
+}
+
+func (r *Registry) generatedDiscriminator(p reflect.Type, value any) (string, bool) {
+	r.mu.RLock()
+	codec, ok := r.generated[p]
+	r.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return codec.discriminatorOf(value)
+}
+
+func (r *Registry) generatedNewFor(p reflect.Type, discriminator string) (any, bool) {
+	r.mu.RLock()
+	codec, ok := r.generated[p]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return codec.newFor(discriminator)
+}
+
+// Overrides the encoding used for T[P] on r specifically, regardless of the
+// default set with r.SetEncoding.
+func SetEncodingForIn[P any](r *Registry, encoding Encoding) {
+	typ := reflect.TypeFor[P]()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byTypeEncoding[typ] = encoding
+}
+
+// Overrides the discriminator key used for T[P] on r specifically,
+// regardless of the default set with r.SetDiscriminatorKey.
+func SetDiscriminatorKeyForIn[P any](r *Registry, key string) {
+	typ := reflect.TypeFor[P]()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byTypeDiscriminatorKey[typ] = key
+}
+
+// Returns the encoding that T[P] will use on r: a per-type override set with
+// SetEncodingForIn, or r's default set with SetEncoding.
+func EncodingForIn[P any](r *Registry) Encoding {
+	return r.encodingFor(reflect.TypeFor[P]())
+}
+
+// EncodingFor is the DefaultRegistry equivalent of EncodingForIn.
+func EncodingFor[P any]() Encoding {
+	return EncodingForIn[P](DefaultRegistry)
+}
+
+// Returns the discriminator key that T[P] will use on r when encoding with
+// EncodingInlineTag or EncodingWrappedTag: a per-type override set with
+// SetDiscriminatorKeyForIn, r's default set with SetDiscriminatorKey, or the
+// mode-specific default.
+func DiscriminatorKeyForIn[P any](r *Registry) string {
+	typ := reflect.TypeFor[P]()
+	return r.discriminatorKeyFor(typ, r.encodingFor(typ))
+}
+
+// DiscriminatorKeyFor is the DefaultRegistry equivalent of DiscriminatorKeyForIn.
+func DiscriminatorKeyFor[P any]() string {
+	return DiscriminatorKeyForIn[P](DefaultRegistry)
+}
+
+// Returns every discriminator registered on r whose implementation actually
+// satisfies P, general and specialized (which take precedence over a general
+// registration for the same discriminator), mapped to the concrete
+// implementing type. Types wired in through RegisterGeneratedIn aren't
+// reflectable and are never included.
+func ImplementationsIn[P any](r *Registry) map[string]reflect.Type {
+	p := reflect.TypeFor[P]()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]reflect.Type)
+	for discriminator, typ := range r.byDiscriminator {
+		if implementsInterface(typ, p) {
+			out[discriminator] = typ
+		}
+	}
+	for discriminator, typ := range r.byDiscriminatorSpecialized[p] {
+		out[discriminator] = typ
+	}
+
+	return out
+}
+
+// Implementations is the DefaultRegistry equivalent of ImplementationsIn.
+func Implementations[P any]() map[string]reflect.Type {
+	return ImplementationsIn[P](DefaultRegistry)
+}
+
+// implementsInterface reports whether typ or a pointer to it implements the
+// interface p, mirroring the check T[P].Discriminated does after
+// reflect.New.
+func implementsInterface(typ, p reflect.Type) bool {
+	if typ.Implements(p) {
+		return true
+	}
+	return reflect.PointerTo(typ).Implements(p)
+}
+
+func (r *Registry) discriminatorFor(specialT, valueT reflect.Type) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	discriminator := ""
+	if special, ok := r.byTypeSpecialized[specialT]; ok {
+		discriminator = special[valueT]
+	}
+	if discriminator == "" {
+		discriminator = r.byType[valueT]
+	}
+
+	return discriminator
+}
+
+func (r *Registry) typeForDiscriminator(specialP reflect.Type, discriminator string) reflect.Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var valueP reflect.Type
+	if special, exists := r.byDiscriminatorSpecialized[specialP]; exists {
+		valueP = special[discriminator]
+	}
+	if valueP == nil {
+		valueP = r.byDiscriminator[discriminator]
+	}
+
+	return valueP
+}
+
+func (r *Registry) encodingFor(p reflect.Type) Encoding {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if encoding, ok := r.byTypeEncoding[p]; ok {
+		return encoding
+	}
+	return r.encoding
+}
+
+func (r *Registry) discriminatorKeyFor(p reflect.Type, encoding Encoding) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if key, ok := r.byTypeDiscriminatorKey[p]; ok {
+		return key
+	}
+	if r.discriminatorKey != "" {
+		return r.discriminatorKey
+	}
+	if encoding == EncodingInlineTag {
+		return defaultInlineKey
+	}
+	return defaultWrappedKey
+}
+
+// hasInlineKey tells taggedDiscriminatorKey whether the object being decoded
+// already has the well-known inline key ("@type") present, so it can be
+// auto-detected when no explicit override is registered.
+func (r *Registry) taggedDiscriminatorKey(typ reflect.Type, hasInlineKey bool) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if key, ok := r.byTypeDiscriminatorKey[typ]; ok {
+		return key
+	}
+	if r.discriminatorKey != "" {
+		return r.discriminatorKey
+	}
+	if hasInlineKey {
+		return defaultInlineKey
+	}
+	return defaultWrappedKey
+}
+
+//===================================================================
+// Decode-scoped registries
+//
+// encoding/json and gopkg.in/yaml.v3 call UnmarshalJSON/UnmarshalYAML with
+// no way back to the *json.Decoder/*yaml.Decoder driving them, so a T[P]
+// with no explicit Registry can't otherwise tell which Registry a
+// Registry.NewJSONDecoder/NewYAMLDecoder call should resolve it against.
+// Decode threads the chosen Registry through as a context.Context keyed by
+// the decoding goroutine, so arbitrarily nested T[P] values decoded during
+// that call resolve against it.
+//===================================================================
+
+type registryContextKey struct{}
+
+var decodeContexts sync.Map // goroutine id (uint64) -> context.Context
+
+// activeDecodeScopes counts live withRegistry calls across every goroutine.
+// contextRegistry checks this before paying for goroutineID()'s
+// runtime.Stack capture and a decodeContexts.Load, so a process that never
+// (or isn't currently) decoding through a Registry.NewJSONDecoder/
+// NewYAMLDecoder call - the plain poly.C/poly.Register path most callers
+// use - costs a single atomic load instead of a stack walk on every
+// Marshal/Unmarshal.
+var activeDecodeScopes atomic.Int64
+
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	b := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// Runs fn with r available to any T[P] decoded on the current goroutine
+// during fn, restoring whatever Registry (if any) was scoped before. If
+// nothing was scoped before (the common case: one Decode call per
+// goroutine, e.g. an HTTP handler), the entry is removed afterward rather
+// than left behind holding context.Background() forever, which would leak
+// one map entry per goroutine for the life of the process.
+func withRegistry(r *Registry, fn func() error) error {
+	activeDecodeScopes.Add(1)
+	defer activeDecodeScopes.Add(-1)
+
+	gid := goroutineID()
+
+	v, hadParent := decodeContexts.Load(gid)
+	var parent context.Context
+	if hadParent {
+		parent = v.(context.Context)
+	} else {
+		parent = context.Background()
+	}
+
+	decodeContexts.Store(gid, context.WithValue(parent, registryContextKey{}, r))
+	defer func() {
+		if hadParent {
+			decodeContexts.Store(gid, parent)
+		} else {
+			decodeContexts.Delete(gid)
+		}
+	}()
+
+	return fn()
+}
+
+func contextRegistry() (*Registry, bool) {
+	if activeDecodeScopes.Load() == 0 {
+		return nil, false
+	}
+
+	v, ok := decodeContexts.Load(goroutineID())
+	if !ok {
+		return nil, false
+	}
+	r, ok := v.(context.Context).Value(registryContextKey{}).(*Registry)
+	return r, ok
+}
+
+// A json.Decoder-like reader that decodes T[P] values without an explicit
+// Registry against r instead of DefaultRegistry, even when they're nested
+// deep inside the decoded value. Create one with Registry.NewJSONDecoder.
+type JSONDecoder struct {
+	dec *json.Decoder
+	r   *Registry
+}
+
+// Creates a JSONDecoder reading from reader that scopes T[P] decoding to r.
+func (r *Registry) NewJSONDecoder(reader io.Reader) *JSONDecoder {
+	return &JSONDecoder{dec: json.NewDecoder(reader), r: r}
+}
+
+// Decodes the next JSON value from the input into v, as json.Decoder.Decode,
+// scoping any T[P] it contains to the Registry this decoder was created with.
+func (d *JSONDecoder) Decode(v any) error {
+	return withRegistry(d.r, func() error {
+		return d.dec.Decode(v)
+	})
+}
+
+// Reports whether there is another element in the current array or object
+// being parsed, as json.Decoder.More.
+func (d *JSONDecoder) More() bool {
+	return d.dec.More()
+}
+
+// A yaml.Decoder-like reader that decodes T[P] values without an explicit
+// Registry against r instead of DefaultRegistry, even when they're nested
+// deep inside the decoded value. Create one with Registry.NewYAMLDecoder.
+type YAMLDecoder struct {
+	dec *yaml.Decoder
+	r   *Registry
+}
+
+// Creates a YAMLDecoder reading from reader that scopes T[P] decoding to r.
+func (r *Registry) NewYAMLDecoder(reader io.Reader) *YAMLDecoder {
+	return &YAMLDecoder{dec: yaml.NewDecoder(reader), r: r}
+}
+
+// Decodes the next YAML document from the input into v, as
+// yaml.Decoder.Decode, scoping any T[P] it contains to the Registry this
+// decoder was created with.
+func (d *YAMLDecoder) Decode(v any) error {
+	return withRegistry(d.r, func() error {
+		return d.dec.Decode(v)
+	})
+}
@@ -0,0 +1,36 @@
+package poly
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithRegistry_NoLeak guards against withRegistry leaving a permanent
+// decodeContexts entry for every goroutine that ever calls Decode, which
+// would be an unbounded leak for a process decoding on a fresh goroutine per
+// request (e.g. net/http). A goroutine that had no registry scoped before
+// its Decode call should have none scoped after it either.
+func TestWithRegistry_NoLeak(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dec := r.NewJSONDecoder(bytes.NewReader([]byte(`[]`)))
+			_ = dec.Decode(&T[any]{})
+		}()
+	}
+	wg.Wait()
+
+	count := 0
+	decodeContexts.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+	assert.Zero(t, count, "withRegistry leaked a decodeContexts entry per goroutine")
+}
@@ -0,0 +1,133 @@
+package poly_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/clickermonkey/poly"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Isolated(t *testing.T) {
+	poly.Reset()
+	poly.Register[SaveJob]("save")
+
+	a := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](a, "a-email")
+
+	b := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](b, "b-email")
+
+	encodedA, err := json.Marshal(poly.CIn[Job](a, EmailJob{Message: "from a"}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["a-email",{"message":"from a"}]`, string(encodedA))
+
+	encodedB, err := json.Marshal(poly.CIn[Job](b, EmailJob{Message: "from b"}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["b-email",{"message":"from b"}]`, string(encodedB))
+
+	// b's discriminator doesn't exist on a, and vice versa.
+	decodedA := poly.T[Job]{Registry: a}
+	assert.Error(t, json.Unmarshal(encodedB, &decodedA))
+
+	decodedB := poly.T[Job]{Registry: b}
+	assert.Error(t, json.Unmarshal(encodedA, &decodedB))
+
+	// DefaultRegistry knows about neither.
+	decodedDefault := poly.T[Job]{}
+	assert.Error(t, json.Unmarshal(encodedA, &decodedDefault))
+}
+
+func TestRegistry_Parallel(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprintf("registry-%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			r := poly.NewRegistry()
+			discriminator := fmt.Sprintf("email-%d", i)
+			poly.RegisterIn[EmailJob](r, discriminator)
+
+			for j := 0; j < 50; j++ {
+				encoded, err := json.Marshal(poly.CIn[Job](r, EmailJob{Message: discriminator}))
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf(`["%s",{"message":"%s"}]`, discriminator, discriminator), string(encoded))
+
+				decoded := poly.T[Job]{Registry: r}
+				assert.NoError(t, json.Unmarshal(encoded, &decoded))
+				assert.Equal(t, discriminator, decoded.Value.Do())
+			}
+		})
+	}
+}
+
+func TestRegistry_JSONDecoder(t *testing.T) {
+	poly.Reset()
+	poly.Register[EmailJob]("email")
+
+	r := poly.NewRegistry()
+	poly.RegisterIn[SaveJob](r, "save")
+
+	dec := r.NewJSONDecoder(bytes.NewReader([]byte(`{"job":["save",{}]}`)))
+	decoded := &HasJob{}
+	assert.NoError(t, dec.Decode(decoded))
+	assert.Equal(t, "saving", decoded.Job.Value.Do())
+
+	// DefaultRegistry's "email" discriminator isn't known to r.
+	other := r.NewJSONDecoder(bytes.NewReader([]byte(`{"job":["email",{"message":"hi"}]}`)))
+	assert.Error(t, other.Decode(&HasJob{}))
+}
+
+func TestRegistry_YAMLDecoder(t *testing.T) {
+	poly.Reset()
+
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+
+	dec := r.NewYAMLDecoder(bytes.NewReader([]byte("job:\n  - email\n  - message: hi\n")))
+	decoded := &HasJob{}
+	assert.NoError(t, dec.Decode(decoded))
+	assert.Equal(t, "hi", decoded.Job.Value.Do())
+}
+
+// TestRegistry_Generated exercises the RegisterGeneratedIn fast path that
+// cmd/polygen wires up, including its fallback to the reflect-based path for
+// discriminators it doesn't know about.
+func TestRegistry_Generated(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterGeneratedIn[Job](
+		r,
+		func(v Job) (string, bool) {
+			switch v.(type) {
+			case EmailJob:
+				return "email", true
+			}
+			return "", false
+		},
+		func(discriminator string) (Job, bool) {
+			switch discriminator {
+			case "email":
+				return &EmailJob{}, true
+			}
+			return nil, false
+		},
+	)
+
+	encoded, err := json.Marshal(poly.CIn[Job](r, EmailJob{Message: "hi"}))
+	assert.NoError(t, err)
+	assert.Equal(t, `["email",{"message":"hi"}]`, string(encoded))
+
+	decoded := poly.T[Job]{Registry: r}
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, "hi", decoded.Value.Do())
+
+	// SaveJob isn't known to the generated codec, so decoding it falls back
+	// to the reflect-based registration path below it.
+	poly.RegisterIn[SaveJob](r, "save")
+
+	decodedSave := poly.T[Job]{Registry: r}
+	assert.NoError(t, json.Unmarshal([]byte(`["save",{}]`), &decodedSave))
+	assert.Equal(t, "saving", decodedSave.Value.Do())
+}
@@ -0,0 +1,207 @@
+// Package schema generates JSON Schema / OpenAPI 3.1 fragments describing
+// the shape of a poly.T[P] value, as a oneOf over every discriminator a
+// Registry knows about for P.
+//
+// The generated Node is a plain struct with json tags, so it serializes
+// directly with encoding/json. It's not a general-purpose schema
+// representation, and a struct scanner (e.g. github.com/invopop/jsonschema)
+// can't produce it on its own, since poly.T[P]'s shape depends on the
+// Registry P is resolved against, not just its Go type. To embed it in a
+// schema produced by such a scanner, generate it separately with
+// ForInterface and splice it in, e.g. as the scanner's Extras for the field.
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/clickermonkey/poly"
+)
+
+// A Node is a JSON Schema fragment. Only the vocabulary this package emits
+// is modeled.
+type Node struct {
+	Type          string           `json:"type,omitempty"`
+	Properties    map[string]*Node `json:"properties,omitempty"`
+	PrefixItems   []*Node          `json:"prefixItems,omitempty"`
+	MinItems      *int             `json:"minItems,omitempty"`
+	MaxItems      *int             `json:"maxItems,omitempty"`
+	Const         any              `json:"const,omitempty"`
+	OneOf         []*Node          `json:"oneOf,omitempty"`
+	Discriminator *Discriminator   `json:"discriminator,omitempty"`
+}
+
+// Discriminator is an OpenAPI 3.1 discriminator object, mapping
+// discriminator strings to the schema $ref of their implementation.
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// ForInterfaceIn builds the schema for T[P] as resolved against r: a oneOf
+// over every discriminator poly.ImplementationsIn[P](r) knows about, shaped
+// according to r's encoding for P (tuple, inline tag, or wrapped tag). The
+// tuple encoding has no OpenAPI discriminator equivalent, so Discriminator
+// is left nil for it; callers relying on plain JSON Schema's oneOf can
+// still use the node as-is.
+func ForInterfaceIn[P any](r *poly.Registry) *Node {
+	impls := poly.ImplementationsIn[P](r)
+	discriminators := make([]string, 0, len(impls))
+	for discriminator := range impls {
+		discriminators = append(discriminators, discriminator)
+	}
+	sort.Strings(discriminators)
+
+	encoding := poly.EncodingForIn[P](r)
+	key := poly.DiscriminatorKeyForIn[P](r)
+
+	node := &Node{OneOf: make([]*Node, 0, len(discriminators))}
+	mapping := make(map[string]string, len(discriminators))
+
+	for _, discriminator := range discriminators {
+		valueSchema := forType(impls[discriminator])
+		mapping[discriminator] = "#/$defs/" + typeName(impls[discriminator])
+
+		if encoding == poly.EncodingTuple {
+			node.OneOf = append(node.OneOf, tupleNode(discriminator, valueSchema))
+		} else {
+			node.OneOf = append(node.OneOf, taggedNode(key, discriminator, valueSchema, encoding))
+		}
+	}
+
+	if encoding != poly.EncodingTuple {
+		node.Discriminator = &Discriminator{PropertyName: key, Mapping: mapping}
+	}
+
+	return node
+}
+
+// ForInterface is the DefaultRegistry equivalent of ForInterfaceIn.
+func ForInterface[P any]() *Node {
+	return ForInterfaceIn[P](poly.DefaultRegistry)
+}
+
+// tupleNode builds the `[discriminator, value]` shape used by
+// poly.EncodingTuple: a 2-item array with a const discriminator at index 0.
+func tupleNode(discriminator string, value *Node) *Node {
+	two := 2
+	return &Node{
+		Type:        "array",
+		PrefixItems: []*Node{{Const: discriminator}, value},
+		MinItems:    &two,
+		MaxItems:    &two,
+	}
+}
+
+// taggedNode builds either object form used by poly.EncodingInlineTag (the
+// discriminator inlined alongside the value's own properties) or
+// poly.EncodingWrappedTag (the value nested under "value"), matching
+// MarshalJSON's behavior for the given encoding.
+func taggedNode(key, discriminator string, value *Node, encoding poly.Encoding) *Node {
+	if encoding == poly.EncodingWrappedTag {
+		return &Node{
+			Type: "object",
+			Properties: map[string]*Node{
+				key:     {Const: discriminator},
+				"value": value,
+			},
+		}
+	}
+
+	node := &Node{Type: "object", Properties: map[string]*Node{key: {Const: discriminator}}}
+	for name, prop := range value.Properties {
+		node.Properties[name] = prop
+	}
+	return node
+}
+
+// forType builds a minimal object schema for typ's exported fields, keyed by
+// their JSON tag name (or field name if untagged). It's not a full
+// json.Marshaler-aware introspection, just enough to describe a
+// poly-registered type's shape for oneOf/discriminator purposes.
+func forType(typ reflect.Type) *Node {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return &Node{Type: jsonType(typ)}
+	}
+
+	node := &Node{Type: "object", Properties: map[string]*Node{}}
+	collectFields(typ, node)
+	return node
+}
+
+func collectFields(typ reflect.Type, node *Node) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectFields(embedded, node)
+				continue
+			}
+		}
+
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		node.Properties[name] = &Node{Type: jsonType(field.Type)}
+	}
+}
+
+// jsonFieldName returns the name encoding/json would use for field, and
+// whether it's included at all (a `json:"-"` tag excludes it).
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok || tag == "" {
+		return field.Name, true
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		return field.Name, true
+	}
+	return name, true
+}
+
+func jsonType(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonType(typ.Elem())
+	default:
+		return "object"
+	}
+}
+
+func typeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
@@ -0,0 +1,186 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/clickermonkey/poly"
+	"github.com/clickermonkey/poly/schema"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// compile compiles node as a JSON Schema under draft 2020-12, the draft
+// OpenAPI 3.1 schemas are based on and the one node's oneOf/prefixItems/
+// discriminator vocabulary targets.
+func compile(t *testing.T, node *schema.Node) *jsonschema.Schema {
+	t.Helper()
+
+	schemaB, err := json.Marshal(node)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var schemaDoc any
+	if !assert.NoError(t, json.Unmarshal(schemaB, &schemaDoc)) {
+		return nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if !assert.NoError(t, compiler.AddResource("schema.json", schemaDoc)) {
+		return nil
+	}
+	compiled, err := compiler.Compile("schema.json")
+	assert.NoError(t, err)
+	return compiled
+}
+
+// assertValidates compiles node and validates encodedJSON against it with a
+// real JSON Schema validator, rather than only asserting on Node's Go shape
+// field-by-field - the validator is what actually needs to accept
+// encodedJSON, applying oneOf/const/prefixItems semantics the hand-written
+// field assertions don't exercise.
+func assertValidates(t *testing.T, node *schema.Node, encodedJSON string) {
+	t.Helper()
+
+	compiled := compile(t, node)
+	if compiled == nil {
+		return
+	}
+
+	var instance any
+	if !assert.NoError(t, json.Unmarshal([]byte(encodedJSON), &instance)) {
+		return
+	}
+	assert.NoError(t, compiled.Validate(instance))
+}
+
+// assertRejects is assertValidates' negative counterpart: encodedJSON must
+// fail validation against node.
+func assertRejects(t *testing.T, node *schema.Node, encodedJSON string) {
+	t.Helper()
+
+	compiled := compile(t, node)
+	if compiled == nil {
+		return
+	}
+
+	var instance any
+	if !assert.NoError(t, json.Unmarshal([]byte(encodedJSON), &instance)) {
+		return
+	}
+	assert.Error(t, compiled.Validate(instance))
+}
+
+type Job interface {
+	Do() string
+}
+
+type EmailJob struct {
+	Message string `json:"message"`
+}
+
+func (e EmailJob) Do() string {
+	return e.Message
+}
+
+type SaveJob struct{}
+
+func (s SaveJob) Do() string {
+	return "saving"
+}
+
+func TestForInterface_Tuple(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	poly.RegisterIn[SaveJob](r, "save")
+
+	node := schema.ForInterfaceIn[Job](r)
+	assert.Nil(t, node.Discriminator)
+	assert.Len(t, node.OneOf, 2)
+
+	for _, alt := range node.OneOf {
+		assert.Equal(t, "array", alt.Type)
+		assert.Len(t, alt.PrefixItems, 2)
+		assert.Equal(t, "object", alt.PrefixItems[1].Type)
+		if alt.PrefixItems[0].Const == "email" {
+			assert.Contains(t, alt.PrefixItems[1].Properties, "message")
+		}
+	}
+
+	// Matches the shape poly.CIn actually produces for this encoding.
+	encoded, err := json.Marshal(poly.CIn[Job](r, EmailJob{Message: "hi"}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["email",{"message":"hi"}]`, string(encoded))
+
+	assertValidates(t, node, string(encoded))
+}
+
+func TestForInterface_InlineTag(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	poly.SetEncodingForIn[Job](r, poly.EncodingInlineTag)
+
+	node := schema.ForInterfaceIn[Job](r)
+	assert.NotNil(t, node.Discriminator)
+	assert.Equal(t, "@type", node.Discriminator.PropertyName)
+	assert.Equal(t, "#/$defs/EmailJob", node.Discriminator.Mapping["email"])
+
+	assert.Len(t, node.OneOf, 1)
+	alt := node.OneOf[0]
+	assert.Equal(t, "object", alt.Type)
+	assert.Equal(t, "email", alt.Properties["@type"].Const)
+	assert.Contains(t, alt.Properties, "message")
+
+	// Matches the shape poly.CIn actually produces for this encoding.
+	encoded, err := json.Marshal(poly.CIn[Job](r, EmailJob{Message: "hi"}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"@type":"email","message":"hi"}`, string(encoded))
+
+	assertValidates(t, node, string(encoded))
+}
+
+func TestForInterface_WrappedTag(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	poly.SetEncodingForIn[Job](r, poly.EncodingWrappedTag)
+
+	node := schema.ForInterfaceIn[Job](r)
+	assert.NotNil(t, node.Discriminator)
+	assert.Equal(t, "type", node.Discriminator.PropertyName)
+
+	alt := node.OneOf[0]
+	assert.Equal(t, "object", alt.Type)
+	assert.Equal(t, "email", alt.Properties["type"].Const)
+	assert.Equal(t, "object", alt.Properties["value"].Type)
+	assert.Contains(t, alt.Properties["value"].Properties, "message")
+
+	// Matches the shape poly.CIn actually produces for this encoding.
+	encoded, err := json.Marshal(poly.CIn[Job](r, EmailJob{Message: "hi"}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"email","value":{"message":"hi"}}`, string(encoded))
+
+	assertValidates(t, node, string(encoded))
+}
+
+// TestForInterface_SchemaRejectsWrongDiscriminator guards against the
+// generated const/discriminator value silently not constraining anything -
+// compiled field-by-field assertions on Node wouldn't catch a validator
+// disagreeing with const semantics, but a real Validate call does.
+func TestForInterface_SchemaRejectsWrongDiscriminator(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+
+	node := schema.ForInterfaceIn[Job](r)
+	assertRejects(t, node, `["not-email",{"message":"hi"}]`)
+	assertRejects(t, node, `["email",{"message":42}]`)
+}
+
+func TestForInterface_OnlyImplementingTypesIncluded(t *testing.T) {
+	r := poly.NewRegistry()
+	poly.RegisterIn[EmailJob](r, "email")
+	poly.RegisterIn[string](r, "not-a-job") // bogus, never implements Job
+
+	node := schema.ForInterfaceIn[Job](r)
+	assert.Len(t, node.OneOf, 1)
+}
@@ -0,0 +1,136 @@
+package poly
+
+import "fmt"
+
+// This file implements a minimal hand-rolled JSON scanner used by
+// unmarshalTupleJSON to locate the discriminator string and the value's raw
+// bytes inside a `[discriminator, value]` tuple without walking the whole
+// thing token-by-token through json.Decoder (which buffers and boxes every
+// token as an any). It only scans far enough to find where each piece
+// starts and ends; decoding what's inside a scanned span is still left to
+// encoding/json, since a value's concrete field layout is arbitrary (see
+// cmd/polygen's Generate doc comment for why that part can't be scanned
+// away the same way).
+
+// skipJSONSpace returns the index of the next non-whitespace byte in b at
+// or after i, per the JSON grammar's whitespace set (space, tab, CR, LF).
+func skipJSONSpace(b []byte, i int) int {
+	for i < len(b) {
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanJSONString scans a JSON string starting at its opening quote b[i] and
+// returns the index just past the closing quote. It tracks backslash
+// escapes only well enough to find that closing quote; it doesn't validate
+// or decode the string's contents.
+func scanJSONString(b []byte, i int) (int, error) {
+	if i >= len(b) || b[i] != '"' {
+		return 0, fmt.Errorf("%w: expected string", ErrInvalidJSON)
+	}
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unterminated string", ErrInvalidJSON)
+}
+
+// scanJSONContainer scans a JSON object or array starting at its opening
+// delimiter and returns the index just past its matching closing delimiter,
+// skipping over string contents so a delimiter byte inside a string doesn't
+// end the container early.
+func scanJSONContainer(b []byte, i int, open, close byte) (int, error) {
+	if i >= len(b) || b[i] != open {
+		return 0, fmt.Errorf("%w: expected %q", ErrInvalidJSON, open)
+	}
+	depth := 0
+	for i < len(b) {
+		switch b[i] {
+		case '"':
+			end, err := scanJSONString(b, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+		i++
+	}
+	return 0, fmt.Errorf("%w: unterminated %q", ErrInvalidJSON, open)
+}
+
+// scanJSONLiteral matches literal (e.g. "true") at b[i:] and returns the
+// index just past it.
+func scanJSONLiteral(b []byte, i int, literal string) (int, error) {
+	if i+len(literal) > len(b) || string(b[i:i+len(literal)]) != literal {
+		return 0, fmt.Errorf("%w: expected %q", ErrInvalidJSON, literal)
+	}
+	return i + len(literal), nil
+}
+
+// scanJSONNumber scans a JSON number starting at b[i] and returns the index
+// just past its last digit/sign/exponent/decimal-point byte.
+func scanJSONNumber(b []byte, i int) (int, error) {
+	start := i
+	for i < len(b) {
+		switch b[i] {
+		case '-', '+', '.', 'e', 'E', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			i++
+		default:
+			if i == start {
+				return 0, fmt.Errorf("%w: expected value", ErrInvalidJSON)
+			}
+			return i, nil
+		}
+	}
+	if i == start {
+		return 0, fmt.Errorf("%w: expected value", ErrInvalidJSON)
+	}
+	return i, nil
+}
+
+// scanJSONValue scans a single JSON value (string, number, object, array,
+// true, false, or null) starting at b[i], skipping leading whitespace, and
+// returns the index just past it. unmarshalTupleJSON uses this to find the
+// tuple's value span so it can hand that span straight to json.Unmarshal,
+// without json.Decoder re-walking it a second time as tokens first.
+func scanJSONValue(b []byte, i int) (int, error) {
+	i = skipJSONSpace(b, i)
+	if i >= len(b) {
+		return 0, fmt.Errorf("%w: unexpected end of value", ErrInvalidJSON)
+	}
+
+	switch b[i] {
+	case '"':
+		return scanJSONString(b, i)
+	case '{':
+		return scanJSONContainer(b, i, '{', '}')
+	case '[':
+		return scanJSONContainer(b, i, '[', ']')
+	case 't':
+		return scanJSONLiteral(b, i, "true")
+	case 'f':
+		return scanJSONLiteral(b, i, "false")
+	case 'n':
+		return scanJSONLiteral(b, i, "null")
+	default:
+		return scanJSONNumber(b, i)
+	}
+}
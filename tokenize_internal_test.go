@@ -0,0 +1,86 @@
+package poly
+
+import "testing"
+
+func TestScanJSONValue(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		wantEnd int
+		wantErr bool
+	}{
+		{name: "string", input: `"hi"`, wantEnd: 4},
+		{name: "string with escaped quote", input: `"a\"b"`, wantEnd: 6},
+		{name: "string with trailing bytes", input: `"hi",{}`, wantEnd: 4},
+		{name: "object", input: `{"a":1,"b":{"c":2}}`, wantEnd: 19},
+		{name: "object with string containing braces", input: `{"a":"}{"}`, wantEnd: 10},
+		{name: "array", input: `[1,[2,3],4]`, wantEnd: 11},
+		{name: "number", input: `-12.5e3`, wantEnd: 7},
+		{name: "number with trailing bytes", input: `42]`, wantEnd: 2},
+		{name: "true", input: `true`, wantEnd: 4},
+		{name: "false", input: `false`, wantEnd: 5},
+		{name: "null", input: `null`, wantEnd: 4},
+		{name: "leading whitespace", input: "  \t42", wantEnd: 5},
+		{name: "unterminated string", input: `"hi`, wantErr: true},
+		{name: "unterminated object", input: `{"a":1`, wantErr: true},
+		{name: "empty", input: ``, wantErr: true},
+		{name: "bad literal", input: `nul`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			end, err := scanJSONValue([]byte(tc.input), 0)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("scanJSONValue(%q) = %d, nil; want error", tc.input, end)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scanJSONValue(%q) = _, %v; want no error", tc.input, err)
+			}
+			if end != tc.wantEnd {
+				t.Fatalf("scanJSONValue(%q) = %d, nil; want %d", tc.input, end, tc.wantEnd)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTupleJSON_RejectsTrailingGarbage(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register[EmailJob]("email")
+
+	d := T[Job]{}
+	err := d.UnmarshalJSON([]byte(`["email",{"message":"hi"} , garbage]`))
+	if err == nil {
+		t.Fatal("expected an error for trailing garbage before the closing ']'")
+	}
+}
+
+func TestUnmarshalTupleJSON_AllowsTrailingWhitespace(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register[EmailJob]("email")
+
+	d := T[Job]{}
+	err := d.UnmarshalJSON([]byte(`["email", {"message":"hi"} ]  `))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Value.Do() != "hi" {
+		t.Fatalf("got %q, want %q", d.Value.Do(), "hi")
+	}
+}
+
+type Job interface {
+	Do() string
+}
+
+type EmailJob struct {
+	Message string `json:"message"`
+}
+
+func (e EmailJob) Do() string {
+	return e.Message
+}